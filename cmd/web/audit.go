@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+)
+
+// audit records an action by the signed-in user.
+func (app *application) audit(r *http.Request, action string, detail map[string]any){
+	app.auditAs(r, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"), action, detail)
+}
+
+// auditAs records an action by actorID, for when the session doesn't say who
+// it was - signups, or logins that haven't happened yet. A failure to write
+// it is logged rather than failing the request, as the action has already
+// happened.
+func (app *application) auditAs(r *http.Request, actorID int, action string, detail map[string]any){
+	err := app.auditLog.Insert(&models.AuditEntry{
+		ActorID: actorID,
+		Action: action,
+		IP: app.clientIP(r),
+		UserAgent: r.UserAgent(),
+		RequestID: app.requestIDFor(r),
+		Detail: detail,
+	})
+	if err != nil{
+		app.errorLog.Printf("audit: %s: %s", action, err)
+	}
+}
+
+// parseAuditTime reads a time given as a date or in RFC 3339. A date on its
+// own means the start of that day in UTC, or with endOfDay, the start of the
+// next so the day is included.
+func parseAuditTime(s string, endOfDay bool) (time.Time, error){
+	if s == ""{
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse("2006-01-02", s)
+	if err == nil{
+		if endOfDay{
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+
+	t, err = time.Parse(time.RFC3339, s)
+	if err != nil{
+		return time.Time{}, fmt.Errorf("%q is not a date (2006-01-02) or time (2006-01-02T15:04:05Z)", s)
+	}
+	return t, nil
+}
+
+// pruneAuditLog deletes entries past audit_retention now and then hourly,
+// unless they are kept forever.
+func (app *application) pruneAuditLog(){
+	if app.cfg.AuditRetention == 0{
+		return
+	}
+
+	go func(){
+		for{
+			n, err := app.auditLog.Prune(time.Now().Add(-app.cfg.AuditRetention))
+			if err != nil{
+				app.errorLog.Printf("audit: prune: %s", err)
+			} else if n > 0{
+				app.infoLog.Printf("audit: pruned %d entries older than %s", n, app.cfg.AuditRetention)
+			}
+			time.Sleep(time.Hour)
+		}
+	}()
+}
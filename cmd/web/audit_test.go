@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+)
+
+func TestParseAuditTime(t *testing.T){
+	tests := []struct{
+		name string
+		value string
+		endOfDay bool
+		want time.Time
+		wantErr bool
+	}{
+		{name: "Empty", value: ""},
+		{name: "Date", value: "2024-03-01", want: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "Date, end of day", value: "2024-03-01", endOfDay: true, want: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{name: "Time", value: "2024-03-01T12:30:00Z", endOfDay: true, want: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)},
+		{name: "Garbage", value: "last tuesday", wantErr: true},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			got, err := parseAuditTime(tt.value, tt.endOfDay)
+			assert.Equal(t, err != nil, tt.wantErr)
+			assert.Equal(t, got.Equal(tt.want), true)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/AVSanjay-12/snippetbox/internal/ldapauth"
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+)
+
+// authChain tries each authenticator in turn. If one is unavailable, e.g. the
+// directory is down, the rest are still tried and its error is only returned
+// if none of them accept the login.
+type authChain []models.Authenticator
+
+func (c authChain) Authenticate(email, password string) (int, error){
+	var firstErr error
+
+	for _, a := range c{
+		id, err := a.Authenticate(email, password)
+		if err == nil{
+			return id, nil
+		}
+		if !errors.Is(err, models.ErrInvalidCredentials) && firstErr == nil{
+			firstErr = err
+		}
+	}
+
+	if firstErr != nil{
+		return 0, firstErr
+	}
+	return 0, models.ErrInvalidCredentials
+}
+
+// newAuthenticator builds the chain of login backends in the configured
+// order.
+func (app *application) newAuthenticator() models.Authenticator{
+	var chain authChain
+
+	for _, backend := range app.cfg.AuthBackends{
+		switch backend{
+		case "local":
+			chain = append(chain, app.users)
+		case "ldap":
+			chain = append(chain, &ldapauth.Authenticator{
+				Config: ldapauth.Config{
+					URL: app.cfg.LDAPURL,
+					StartTLS: app.cfg.LDAPStartTLS,
+					BindDN: app.cfg.LDAPBindDN,
+					BindPassword: app.cfg.LDAPBindPassword,
+					BaseDN: app.cfg.LDAPBaseDN,
+					UserFilter: app.cfg.LDAPUserFilter,
+					NameAttr: app.cfg.LDAPNameAttr,
+					EmailAttr: app.cfg.LDAPEmailAttr,
+					RequiredGroup: app.cfg.LDAPRequiredGroup,
+					Timeout: app.cfg.LDAPTimeout,
+				},
+				Provision: func(name, email string) (int, error){
+					return app.externalUser(context.Background(), name, email)
+				},
+			})
+		}
+	}
+
+	return chain
+}
+
+// externalUser returns the local user for someone whose email address was
+// vouched for elsewhere - by an identity provider or a directory - creating
+// one if there isn't one yet.
+func (app *application) externalUser(ctx context.Context, name, email string) (int, error){
+	user, err := app.users.GetByEmail(email)
+	if err != nil{
+		if errors.Is(err, models.ErrNoRecord){
+			return app.users.InsertExternal(name, email)
+		}
+		return 0, err
+	}
+
+	// Someone could have signed up with this address before its owner
+	// arrived, so sign them out and take their password away.
+	if !user.EmailVerified{
+		err = app.users.Reclaim(user.ID)
+		if err != nil{
+			return 0, err
+		}
+		err = app.totp.Disable(user.ID)
+		if err != nil{
+			return 0, err
+		}
+		err = app.revokeSessions(user.ID, "")
+		if err != nil{
+			return 0, err
+		}
+	}
+
+	return user.ID, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+)
+
+type fakeAuthenticator struct{
+	id int
+	err error
+}
+
+func (a fakeAuthenticator) Authenticate(email, password string) (int, error){
+	return a.id, a.err
+}
+
+func TestAuthChain(t *testing.T){
+	errDown := errors.New("directory unavailable")
+	rejects := fakeAuthenticator{err: models.ErrInvalidCredentials}
+
+	tests := []struct{
+		name string
+		chain authChain
+		wantID int
+		wantErr error
+	}{
+		{name: "First accepts", chain: authChain{fakeAuthenticator{id: 1}, fakeAuthenticator{id: 2}}, wantID: 1},
+		{name: "Falls through", chain: authChain{rejects, fakeAuthenticator{id: 2}}, wantID: 2},
+		{name: "All reject", chain: authChain{rejects, rejects}, wantErr: models.ErrInvalidCredentials},
+		{name: "Unavailable then accepts", chain: authChain{fakeAuthenticator{err: errDown}, fakeAuthenticator{id: 2}}, wantID: 2},
+		{name: "Unavailable then rejects", chain: authChain{fakeAuthenticator{err: errDown}, rejects}, wantErr: errDown},
+		{name: "Empty", chain: authChain{}, wantErr: models.ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			id, err := tt.chain.Authenticate("alice@example.com", "password")
+			assert.Equal(t, id, tt.wantID)
+			assert.Equal(t, errors.Is(err, tt.wantErr), true)
+		})
+	}
+}
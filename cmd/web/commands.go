@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+)
+
+// runCommand dispatches the command-line subcommands, i.e. anything other
+// than starting the server.
+func runCommand(name string, args []string) error{
+	switch name{
+	case "config":
+		return runConfigCommand(args)
+	case "user":
+		return runUserCommand(args)
+	case "audit":
+		return runAuditCommand(args)
+	}
+	return fmt.Errorf("unknown command %q", name)
+}
+
+const userUsage = `usage:
+  snippetbox user set-role <email> <admin|member> [flags]
+  snippetbox user export <email> [-format json|zip] [-o file] [flags]
+  snippetbox user delete <email> [-snippets delete|anonymize] [flags]`
+
+func runUserCommand(args []string) error{
+	if len(args) < 2{
+		return errors.New(userUsage)
+	}
+
+	switch args[0]{
+	case "set-role":
+		return runUserSetRole(args[1:])
+	case "export":
+		return runUserExport(args[1:])
+	case "delete":
+		return runUserDelete(args[1:])
+	}
+	return errors.New(userUsage)
+}
+
+// openUser loads the config from args, connects to the database and looks
+// up the user with the email address. The caller closes db.
+func openUser(fs *flag.FlagSet, email string, args []string) (*sql.DB, *models.User, error){
+	cfg, err := loadConfigFlags(fs, args, os.LookupEnv)
+	if err != nil{
+		return nil, nil, err
+	}
+
+	db, err := openDB(cfg.DSN)
+	if err != nil{
+		return nil, nil, err
+	}
+
+	user, err := (&models.UserModel{DB: db}).GetByEmail(email)
+	if err != nil{
+		db.Close()
+		if errors.Is(err, models.ErrNoRecord){
+			return nil, nil, fmt.Errorf("no user with email %s", email)
+		}
+		return nil, nil, err
+	}
+	return db, user, nil
+}
+
+// This is how the first admin is made.
+func runUserSetRole(args []string) error{
+	if len(args) < 2{
+		return errors.New(userUsage)
+	}
+	email, role := args[0], args[1]
+	if !models.ValidRole(role){
+		return fmt.Errorf("unknown role %q (want admin or member)", role)
+	}
+
+	db, user, err := openUser(flag.NewFlagSet("snippetbox user set-role", flag.ContinueOnError), email, args[2:])
+	if err != nil{
+		return err
+	}
+	defer db.Close()
+
+	err = (&models.UserModel{DB: db}).SetRole(user.ID, role)
+	if err != nil{
+		return err
+	}
+
+	fmt.Printf("%s is now %s\n", user.Email, role)
+	return nil
+}
+
+// runUserExport writes out a user's data, as they would download it
+// themselves, for requests that come in some other way.
+func runUserExport(args []string) error{
+	fs := flag.NewFlagSet("snippetbox user export", flag.ContinueOnError)
+	format := fs.String("format", "json", "json, or zip for an archive")
+	output := fs.String("o", "", "File to write to instead of stdout")
+
+	db, user, err := openUser(fs, args[0], args[1:])
+	if err != nil{
+		return err
+	}
+	defer db.Close()
+
+	if *format != "json" && *format != "zip"{
+		return fmt.Errorf("-format: want json or zip, not %q", *format)
+	}
+
+	auditLog := &models.AuditModel{DB: db}
+
+	export, err := newAccountExport(&models.UserModel{DB: db}, &models.SnippetModel{DB: db}, auditLog, user.ID)
+	if err != nil{
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != ""{
+		f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil{
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "zip"{
+		err = export.writeZIP(w)
+	} else{
+		err = export.writeJSON(w)
+	}
+	if err != nil{
+		return err
+	}
+
+	return auditLog.Insert(&models.AuditEntry{
+		Action: models.AuditAccountExport,
+		Detail: map[string]any{"user_id": user.ID, "format": *format, "via": "cli"},
+	})
+}
+
+func runUserDelete(args []string) error{
+	fs := flag.NewFlagSet("snippetbox user delete", flag.ContinueOnError)
+	snippets := fs.String("snippets", "delete", "What to do with the user's snippets: delete, or anonymize to keep them without an owner")
+
+	db, user, err := openUser(fs, args[0], args[1:])
+	if err != nil{
+		return err
+	}
+	defer db.Close()
+
+	if *snippets != "delete" && *snippets != "anonymize"{
+		return fmt.Errorf("-snippets: want delete or anonymize, not %q", *snippets)
+	}
+
+	// Any sessions they have stop working, as their records go too
+	err = (&models.UserModel{DB: db}).Delete(user.ID, *snippets == "anonymize")
+	if err != nil{
+		return err
+	}
+
+	err = (&models.AuditModel{DB: db}).Insert(&models.AuditEntry{
+		Action: models.AuditAccountDelete,
+		Detail: map[string]any{"user_id": user.ID, "email": user.Email, "snippets": *snippets, "via": "cli"},
+	})
+	if err != nil{
+		return err
+	}
+
+	fmt.Printf("Deleted %s\n", user.Email)
+	return nil
+}
+
+// Usage: snippetbox audit export [-user email] [-since date] [-until date] [flags]
+//
+// Writes matching entries to stdout as JSON, one per line, oldest first.
+func runAuditCommand(args []string) error{
+	if len(args) == 0 || args[0] != "export"{
+		return errors.New("usage: snippetbox audit export [-user email] [-since date] [-until date] [flags]")
+	}
+
+	fs := flag.NewFlagSet("snippetbox audit export", flag.ContinueOnError)
+	email := fs.String("user", "", "Only entries by the user with this email")
+	since := fs.String("since", "", "Only entries from this date (2006-01-02) or time (RFC 3339) on")
+	until := fs.String("until", "", "Only entries up to the end of this date, or before this time")
+
+	cfg, err := loadConfigFlags(fs, args[1:], os.LookupEnv)
+	if err != nil{
+		return err
+	}
+
+	var filter models.AuditFilter
+
+	filter.Since, err = parseAuditTime(*since, false)
+	if err != nil{
+		return fmt.Errorf("-since: %w", err)
+	}
+	filter.Until, err = parseAuditTime(*until, true)
+	if err != nil{
+		return fmt.Errorf("-until: %w", err)
+	}
+
+	db, err := openDB(cfg.DSN)
+	if err != nil{
+		return err
+	}
+	defer db.Close()
+
+	if *email != ""{
+		user, err := (&models.UserModel{DB: db}).GetByEmail(*email)
+		if err != nil{
+			if errors.Is(err, models.ErrNoRecord){
+				return fmt.Errorf("no user with email %s", *email)
+			}
+			return err
+		}
+		filter.ActorID = user.ID
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	enc := json.NewEncoder(w)
+
+	err = (&models.AuditModel{DB: db}).Each(filter, func(e *models.AuditEntry) error{
+		return enc.Encode(e)
+	})
+	if err != nil{
+		return err
+	}
+	return w.Flush()
+}
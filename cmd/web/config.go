@@ -0,0 +1,623 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AVSanjay-12/snippetbox/internal/passhash"
+	"github.com/AVSanjay-12/snippetbox/internal/validator"
+	"github.com/BurntSushi/toml"
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Prefix for environment variables, e.g. SNIPPETBOX_ADDR or SNIPPETBOX_TLS_CERT_FILE
+const envPrefix = "SNIPPETBOX_"
+
+type config struct{
+	Addr string
+	DSN string
+	TLSMode string
+	TLSCertFile string
+	TLSKeyFile string
+	RedirectAddr string
+	ACMEHosts []string
+	ACMECacheDir string
+	ACMEEmail string
+	ACMEDirectoryURL string
+	ACMECAFile string
+	SessionLifetime time.Duration
+	RememberMeLifetime time.Duration
+	IdleTimeout time.Duration
+	ReadTimeout time.Duration
+	WriteTimeout time.Duration
+	ShutdownDrainDelay time.Duration
+	ShutdownTimeout time.Duration
+	PasswordHasher string
+	BcryptCost int
+	Argon2Memory int
+	Argon2Iterations int
+	Argon2Parallelism int
+	PasswordMinLength int
+	PasswordMaxLength int
+	PasswordBreachedCheck bool
+	PasswordBreachedDir string
+	LoginThrottleStore string
+	LoginMaxFailures int
+	LoginMaxFailuresIP int
+	LoginLockout time.Duration
+	LoginLockoutMax time.Duration
+	LoginFailureWindow time.Duration
+	BaseURL string
+	SecretKey string
+	VerifyTokenLifetime time.Duration
+	ResetTokenLifetime time.Duration
+	Mailer string
+	MailDir string
+	MailSender string
+	SMTPHost string
+	SMTPPort int
+	SMTPUsername string
+	SMTPPassword string
+	TrustedProxies []string
+	RateLimitEnabled bool
+	RateLimitGlobal rateLimit
+	RateLimitRead rateLimit
+	RateLimitSignup rateLimit
+	RateLimitLogin rateLimit
+	RateLimitCreate rateLimit
+	RateLimitRecovery rateLimit
+	OIDCIssuer string
+	OIDCClientID string
+	OIDCClientSecret string
+	OIDCRedirectURL string
+	OIDCAllowedDomains []string
+	OIDCDisplayName string
+	AuthBackends []string
+	LDAPURL string
+	LDAPStartTLS bool
+	LDAPBindDN string
+	LDAPBindPassword string
+	LDAPBaseDN string
+	LDAPUserFilter string
+	LDAPNameAttr string
+	LDAPEmailAttr string
+	LDAPRequiredGroup string
+	LDAPTimeout time.Duration
+	AuditRetention time.Duration
+	UploadMaxSize int
+	Dev bool
+	UIDir string
+}
+
+// Built-in defaults - the lowest configuration layer
+func defaultConfig() *config{
+	return &config{
+		Addr: ":4000",
+		DSN: "web:pass@/snippetbox?parseTime=true",
+		TLSMode: tlsModeFile,
+		TLSCertFile: "./tls/cert.pem",
+		TLSKeyFile: "./tls/key.pem",
+		ACMECacheDir: "./tls/acme",
+		ACMEDirectoryURL: autocert.DefaultACMEDirectory,
+		SessionLifetime: 12 * time.Hour,
+		RememberMeLifetime: 30 * 24 * time.Hour,
+		IdleTimeout: time.Minute,
+		ReadTimeout: 5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		ShutdownDrainDelay: 5 * time.Second,
+		ShutdownTimeout: 20 * time.Second,
+		PasswordHasher: "bcrypt",
+		BcryptCost: 12,
+		Argon2Memory: 19 * 1024,
+		Argon2Iterations: 2,
+		Argon2Parallelism: 1,
+		PasswordMinLength: 8,
+		PasswordMaxLength: validator.MaxPasswordBytes,
+		PasswordBreachedCheck: true,
+		LoginThrottleStore: "memory",
+		LoginMaxFailures: 5,
+		LoginMaxFailuresIP: 50,
+		LoginLockout: time.Minute,
+		LoginLockoutMax: time.Hour,
+		UploadMaxSize: 1024,
+		LoginFailureWindow: 24 * time.Hour,
+		BaseURL: "https://localhost:4000",
+		VerifyTokenLifetime: 48 * time.Hour,
+		ResetTokenLifetime: 30 * time.Minute,
+		Mailer: "log",
+		MailSender: "Snippetbox <no-reply@snippetbox.example>",
+		SMTPPort: 587,
+		RateLimitEnabled: true,
+		RateLimitGlobal: rateLimit{Requests: 100, Per: time.Second},
+		RateLimitRead: rateLimit{Requests: 120, Per: time.Minute},
+		RateLimitSignup: rateLimit{Requests: 5, Per: time.Hour},
+		RateLimitLogin: rateLimit{Requests: 10, Per: time.Minute},
+		RateLimitCreate: rateLimit{Requests: 30, Per: time.Hour},
+		RateLimitRecovery: rateLimit{Requests: 5, Per: time.Hour},
+		OIDCDisplayName: "single sign-on",
+		AuthBackends: []string{"local"},
+		LDAPUserFilter: "(mail=%s)",
+		LDAPNameAttr: "cn",
+		LDAPEmailAttr: "mail",
+		LDAPTimeout: 5 * time.Second,
+	}
+}
+
+// A setting ties one config field to its key. The key is used as-is in the
+// config file, upper-cased for the environment and hyphenated for flags.
+type setting struct{
+	key string
+	usage string
+	ptr any
+	secret bool
+}
+
+func (cfg *config) settings() []setting{
+	return []setting{
+		{key: "addr", usage: "HTTP network address", ptr: &cfg.Addr},
+		{key: "dsn", usage: "MySQL data source name", ptr: &cfg.DSN, secret: true},
+		{key: "tls_mode", usage: "TLS mode: file, acme or off (plain HTTP behind a TLS-terminating proxy)", ptr: &cfg.TLSMode},
+		{key: "tls_cert_file", usage: "TLS certificate file (tls mode file)", ptr: &cfg.TLSCertFile},
+		{key: "tls_key_file", usage: "TLS private key file (tls mode file)", ptr: &cfg.TLSKeyFile},
+		{key: "redirect_addr", usage: "Plain HTTP address that redirects to HTTPS and answers ACME HTTP-01 challenges, e.g. :80 (empty disables)", ptr: &cfg.RedirectAddr},
+		{key: "acme_hosts", usage: "Comma-separated hostnames certificates may be requested for", ptr: &cfg.ACMEHosts},
+		{key: "acme_cache_dir", usage: "Directory for ACME account keys and certificates", ptr: &cfg.ACMECacheDir},
+		{key: "acme_email", usage: "Contact email for the ACME account", ptr: &cfg.ACMEEmail},
+		{key: "acme_directory_url", usage: "ACME directory URL, e.g. a local Pebble instance for testing", ptr: &cfg.ACMEDirectoryURL},
+		{key: "acme_ca_file", usage: "PEM file of extra roots to trust for the ACME directory", ptr: &cfg.ACMECAFile},
+		{key: "session_lifetime", usage: "Session lifetime", ptr: &cfg.SessionLifetime},
+		{key: "remember_me_lifetime", usage: "How long \"remember me\" keeps a user logged in (0 turns it off)", ptr: &cfg.RememberMeLifetime},
+		{key: "idle_timeout", usage: "HTTP keep-alive idle timeout", ptr: &cfg.IdleTimeout},
+		{key: "read_timeout", usage: "HTTP request read timeout", ptr: &cfg.ReadTimeout},
+		{key: "write_timeout", usage: "HTTP response write timeout", ptr: &cfg.WriteTimeout},
+		{key: "shutdown_drain_delay", usage: "Time to fail readiness before closing listeners", ptr: &cfg.ShutdownDrainDelay},
+		{key: "shutdown_timeout", usage: "Time allowed for in-flight requests on shutdown", ptr: &cfg.ShutdownTimeout},
+		{key: "password_hasher", usage: "Algorithm for new password hashes: bcrypt or argon2id. Existing hashes keep working and are upgraded at login.", ptr: &cfg.PasswordHasher},
+		{key: "bcrypt_cost", usage: "bcrypt cost for new password hashes", ptr: &cfg.BcryptCost},
+		{key: "argon2_memory", usage: "argon2id memory for new password hashes, in KiB", ptr: &cfg.Argon2Memory},
+		{key: "argon2_iterations", usage: "argon2id iterations for new password hashes", ptr: &cfg.Argon2Iterations},
+		{key: "argon2_parallelism", usage: "argon2id parallelism for new password hashes", ptr: &cfg.Argon2Parallelism},
+		{key: "password_min_length", usage: "Minimum length of new passwords, in characters", ptr: &cfg.PasswordMinLength},
+		{key: "password_max_length", usage: "Maximum length of new passwords, in bytes (bcrypt ignores anything past 72)", ptr: &cfg.PasswordMaxLength},
+		{key: "password_breached_check", usage: "Reject new passwords found in breaches", ptr: &cfg.PasswordBreachedCheck},
+		{key: "password_breached_dir", usage: "Directory of Pwned Passwords range files (e.g. 5BAA6.txt) to check as well as the bundled list", ptr: &cfg.PasswordBreachedDir},
+		{key: "login_throttle_store", usage: "Where failed logins are tracked: memory or database", ptr: &cfg.LoginThrottleStore},
+		{key: "login_max_failures", usage: "Failed logins per account before it is locked", ptr: &cfg.LoginMaxFailures},
+		{key: "login_max_failures_ip", usage: "Failed logins per client IP before it is locked", ptr: &cfg.LoginMaxFailuresIP},
+		{key: "login_lockout", usage: "First lockout period, doubled for each further failure", ptr: &cfg.LoginLockout},
+		{key: "login_lockout_max", usage: "Longest lockout period", ptr: &cfg.LoginLockoutMax},
+		{key: "login_failure_window", usage: "How long a failed login counts towards a lockout", ptr: &cfg.LoginFailureWindow},
+		{key: "base_url", usage: "Public URL of the site, used for links in emails", ptr: &cfg.BaseURL},
+		{key: "secret_key", usage: "Key for signing email links; a random one is used if empty, so links break on restart", ptr: &cfg.SecretKey, secret: true},
+		{key: "verify_token_lifetime", usage: "How long email confirmation links stay valid", ptr: &cfg.VerifyTokenLifetime},
+		{key: "reset_token_lifetime", usage: "How long password reset links stay valid", ptr: &cfg.ResetTokenLifetime},
+		{key: "mailer", usage: "How email is sent: smtp, or log to write it to mail_dir or the info log", ptr: &cfg.Mailer},
+		{key: "mail_dir", usage: "Directory the log mailer writes .eml files to (empty logs them, with link tokens redacted unless dev is set)", ptr: &cfg.MailDir},
+		{key: "mail_sender", usage: "From address for outgoing email", ptr: &cfg.MailSender},
+		{key: "smtp_host", usage: "SMTP server host", ptr: &cfg.SMTPHost},
+		{key: "smtp_port", usage: "SMTP server port", ptr: &cfg.SMTPPort},
+		{key: "smtp_username", usage: "SMTP username", ptr: &cfg.SMTPUsername},
+		{key: "smtp_password", usage: "SMTP password", ptr: &cfg.SMTPPassword, secret: true},
+		{key: "trusted_proxies", usage: "Comma-separated proxy addresses or CIDR ranges whose X-Forwarded-For is believed", ptr: &cfg.TrustedProxies},
+		{key: "rate_limit_enabled", usage: "Enable request rate limiting", ptr: &cfg.RateLimitEnabled},
+		{key: "rate_limit_global", usage: "Limit on all requests per client IP, as requests/period", ptr: &cfg.RateLimitGlobal},
+		{key: "rate_limit_read", usage: "Limit on viewing snippets per client", ptr: &cfg.RateLimitRead},
+		{key: "rate_limit_signup", usage: "Limit on signups per client", ptr: &cfg.RateLimitSignup},
+		{key: "rate_limit_login", usage: "Limit on login attempts per client", ptr: &cfg.RateLimitLogin},
+		{key: "rate_limit_create", usage: "Limit on snippet creation per client", ptr: &cfg.RateLimitCreate},
+		{key: "rate_limit_recovery", usage: "Limit on password reset and email confirmation requests per client", ptr: &cfg.RateLimitRecovery},
+		{key: "oidc_issuer", usage: "OpenID Connect issuer URL for single sign-on (empty disables)", ptr: &cfg.OIDCIssuer},
+		{key: "oidc_client_id", usage: "OpenID Connect client ID", ptr: &cfg.OIDCClientID},
+		{key: "oidc_client_secret", usage: "OpenID Connect client secret", ptr: &cfg.OIDCClientSecret, secret: true},
+		{key: "oidc_redirect_url", usage: "Callback URL registered with the provider (default base_url + /user/login/oidc/callback)", ptr: &cfg.OIDCRedirectURL},
+		{key: "oidc_allowed_domains", usage: "Comma-separated email domains allowed to sign in with OpenID Connect (empty allows any)", ptr: &cfg.OIDCAllowedDomains},
+		{key: "oidc_display_name", usage: "Provider name shown on the login button", ptr: &cfg.OIDCDisplayName},
+		{key: "auth_backends", usage: "Comma-separated login backends to try in order: local, ldap", ptr: &cfg.AuthBackends},
+		{key: "ldap_url", usage: "LDAP server URL, e.g. ldaps://ldap.example.com", ptr: &cfg.LDAPURL},
+		{key: "ldap_start_tls", usage: "Upgrade ldap:// connections with StartTLS", ptr: &cfg.LDAPStartTLS},
+		{key: "ldap_bind_dn", usage: "DN to bind as to search for users (empty searches anonymously)", ptr: &cfg.LDAPBindDN},
+		{key: "ldap_bind_password", usage: "Password for ldap_bind_dn", ptr: &cfg.LDAPBindPassword, secret: true},
+		{key: "ldap_base_dn", usage: "DN users are searched for under", ptr: &cfg.LDAPBaseDN},
+		{key: "ldap_user_filter", usage: "Filter finding a user by the email they log in with; %s is replaced by the email", ptr: &cfg.LDAPUserFilter},
+		{key: "ldap_name_attr", usage: "Attribute holding the user's name", ptr: &cfg.LDAPNameAttr},
+		{key: "ldap_email_attr", usage: "Attribute holding the user's email address", ptr: &cfg.LDAPEmailAttr},
+		{key: "ldap_required_group", usage: "DN of a group users must be a member of to log in (empty allows any)", ptr: &cfg.LDAPRequiredGroup},
+		{key: "ldap_timeout", usage: "Timeout for LDAP connections and requests", ptr: &cfg.LDAPTimeout},
+		{key: "audit_retention", usage: "How long audit log entries are kept, e.g. 8760h (0 keeps them forever)", ptr: &cfg.AuditRetention},
+		{key: "upload_max_size", usage: "Largest snippet that can be created, including uploaded files, in KiB", ptr: &cfg.UploadMaxSize},
+		{key: "dev", usage: "Development mode: re-parse templates from ui_dir on every request", ptr: &cfg.Dev},
+		{key: "ui_dir", usage: "Serve templates and static files from this directory instead of the embedded copy, e.g. ./ui", ptr: &cfg.UIDir},
+	}
+}
+
+func (s setting) flagName() string{
+	return strings.ReplaceAll(s.key, "_", "-")
+}
+
+func (s setting) envName() string{
+	return envPrefix + strings.ToUpper(s.key)
+}
+
+// String and Set make a setting usable as a flag.Value.
+func (s setting) String() string{
+	switch p := s.ptr.(type){
+	case *string:
+		return *p
+	case *int:
+		return strconv.Itoa(*p)
+	case *bool:
+		return strconv.FormatBool(*p)
+	case *time.Duration:
+		return p.String()
+	case *[]string:
+		return strings.Join(*p, ",")
+	case *rateLimit:
+		return p.String()
+	}
+	return ""
+}
+
+func (s setting) Set(value string) error{
+	switch p := s.ptr.(type){
+	case *string:
+		*p = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil{
+			return fmt.Errorf("%s: %q is not a whole number", s.key, value)
+		}
+		*p = n
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil{
+			return fmt.Errorf("%s: %q is not true or false", s.key, value)
+		}
+		*p = b
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil{
+			return fmt.Errorf("%s: %q is not a duration such as 30s or 12h", s.key, value)
+		}
+		*p = d
+	case *[]string:
+		*p = nil
+		for _, v := range strings.Split(value, ","){
+			if v = strings.TrimSpace(v); v != ""{
+				*p = append(*p, v)
+			}
+		}
+	case *rateLimit:
+		l, err := parseRateLimit(value)
+		if err != nil{
+			return fmt.Errorf("%s: %w", s.key, err)
+		}
+		*p = l
+	}
+	return nil
+}
+
+// Bool settings can be given as a bare flag, without "=true".
+func (s setting) IsBoolFlag() bool{
+	_, ok := s.ptr.(*bool)
+	return ok
+}
+
+// Values decoded from TOML arrive typed, so bring them back to the string
+// form Set understands.
+func (s setting) setTOML(value any) error{
+	switch v := value.(type){
+	case string:
+		return s.Set(v)
+	case int64:
+		return s.Set(strconv.FormatInt(v, 10))
+	case bool:
+		return s.Set(strconv.FormatBool(v))
+	case []any:
+		items := make([]string, len(v))
+		for i := range v{
+			items[i] = fmt.Sprint(v[i])
+		}
+		return s.Set(strings.Join(items, ","))
+	}
+	return fmt.Errorf("%s: unsupported value %v", s.key, value)
+}
+
+// loadConfig layers defaults, the config file, SNIPPETBOX_* environment
+// variables and finally command-line flags, then validates the result.
+func loadConfig(args []string, lookupEnv func(string) (string, bool)) (*config, error){
+	return loadConfigFlags(flag.NewFlagSet("snippetbox", flag.ContinueOnError), args, lookupEnv)
+}
+
+// loadConfigFlags is loadConfig for subcommands with flags of their own,
+// which they define on fs beforehand.
+func loadConfigFlags(fs *flag.FlagSet, args []string, lookupEnv func(string) (string, bool)) (*config, error){
+	cfg := defaultConfig()
+
+	// Flags are parsed first (the config file path is one of them) but into a
+	// scratch copy, so they can be applied on top of the other layers.
+	flagged := defaultConfig()
+	configFile, _ := lookupEnv(envPrefix + "CONFIG")
+
+	fs.StringVar(&configFile, "config", configFile, "Path to a TOML config file")
+	for _, s := range flagged.settings(){
+		fs.Var(s, s.flagName(), s.usage)
+	}
+
+	err := fs.Parse(args)
+	if err != nil{
+		return nil, err
+	}
+
+	if configFile != ""{
+		err = cfg.loadFile(configFile)
+		if err != nil{
+			return nil, err
+		}
+	}
+
+	for _, s := range cfg.settings(){
+		if value, ok := lookupEnv(s.envName()); ok{
+			err = s.Set(value)
+			if err != nil{
+				return nil, fmt.Errorf("config: %s: %w", s.envName(), err)
+			}
+		}
+	}
+
+	passed := map[string]bool{}
+	fs.Visit(func(f *flag.Flag){
+		passed[f.Name] = true
+	})
+	src := flagged.settings()
+	for i, s := range cfg.settings(){
+		if passed[s.flagName()]{
+			s.Set(src[i].String())
+		}
+	}
+
+	err = cfg.validate()
+	if err != nil{
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile reads a TOML file. Tables are flattened, so tls_cert_file can also
+// be written as cert_file under a [tls] table.
+func (cfg *config) loadFile(path string) error{
+	var raw map[string]any
+	_, err := toml.DecodeFile(path, &raw)
+	if err != nil{
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+
+	values := map[string]any{}
+	flatten("", raw, values)
+
+	byKey := map[string]setting{}
+	for _, s := range cfg.settings(){
+		byKey[s.key] = s
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values{
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys{
+		s, ok := byKey[k]
+		if !ok{
+			return fmt.Errorf("config: %s: unknown setting %q", path, k)
+		}
+		err = s.setTOML(values[k])
+		if err != nil{
+			return fmt.Errorf("config: %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func flatten(prefix string, in map[string]any, out map[string]any){
+	for k, v := range in{
+		if prefix != ""{
+			k = prefix + "_" + k
+		}
+		if table, ok := v.(map[string]any); ok{
+			flatten(k, table, out)
+			continue
+		}
+		out[k] = v
+	}
+}
+
+// passwordPolicy is how password hashes are made and checked.
+func (cfg *config) passwordPolicy() *passhash.Policy{
+	if cfg.PasswordHasher == "argon2id"{
+		return &passhash.Policy{Current: &passhash.Argon2id{
+			Memory: uint32(cfg.Argon2Memory),
+			Iterations: uint32(cfg.Argon2Iterations),
+			Parallelism: uint8(cfg.Argon2Parallelism),
+		}}
+	}
+	return &passhash.Policy{Current: &passhash.Bcrypt{Cost: cfg.BcryptCost}}
+}
+
+func (cfg *config) validate() error{
+	var errs []error
+	check := func(ok bool, format string, args ...any){
+		if !ok{
+			errs = append(errs, fmt.Errorf("config: "+format, args...))
+		}
+	}
+
+	check(cfg.Addr != "", "addr must not be empty")
+	check(cfg.DSN != "", "dsn must not be empty")
+	if cfg.DSN != ""{
+		_, err := mysql.ParseDSN(cfg.DSN)
+		check(err == nil, "dsn is not a valid MySQL data source name: %v", err)
+	}
+	switch cfg.TLSMode{
+	case tlsModeFile:
+		check(cfg.TLSCertFile != "", "tls_cert_file must not be empty when tls_mode is file")
+		check(cfg.TLSKeyFile != "", "tls_key_file must not be empty when tls_mode is file")
+	case tlsModeACME:
+		check(len(cfg.ACMEHosts) > 0, "acme_hosts must list at least one hostname when tls_mode is acme")
+		check(cfg.ACMECacheDir != "", "acme_cache_dir must not be empty when tls_mode is acme")
+		check(cfg.ACMEDirectoryURL != "", "acme_directory_url must not be empty when tls_mode is acme")
+	case tlsModeOff:
+	default:
+		check(false, "tls_mode must be one of file, acme or off (got %q)", cfg.TLSMode)
+	}
+	check(cfg.SessionLifetime > 0, "session_lifetime must be positive (got %s)", cfg.SessionLifetime)
+	check(cfg.RememberMeLifetime >= 0, "remember_me_lifetime must not be negative (got %s)", cfg.RememberMeLifetime)
+	check(cfg.IdleTimeout > 0, "idle_timeout must be positive (got %s)", cfg.IdleTimeout)
+	check(cfg.ReadTimeout > 0, "read_timeout must be positive (got %s)", cfg.ReadTimeout)
+	check(cfg.WriteTimeout > 0, "write_timeout must be positive (got %s)", cfg.WriteTimeout)
+	check(cfg.ShutdownDrainDelay >= 0, "shutdown_drain_delay must not be negative (got %s)", cfg.ShutdownDrainDelay)
+	check(cfg.ShutdownTimeout > 0, "shutdown_timeout must be positive (got %s)", cfg.ShutdownTimeout)
+	check(cfg.PasswordHasher == "bcrypt" || cfg.PasswordHasher == "argon2id", "password_hasher must be bcrypt or argon2id (got %q)", cfg.PasswordHasher)
+	check(cfg.BcryptCost >= bcrypt.MinCost && cfg.BcryptCost <= bcrypt.MaxCost,
+		"bcrypt_cost must be between %d and %d (got %d)", bcrypt.MinCost, bcrypt.MaxCost, cfg.BcryptCost)
+	check(cfg.Argon2Parallelism >= 1 && cfg.Argon2Parallelism <= 255, "argon2_parallelism must be between 1 and 255 (got %d)", cfg.Argon2Parallelism)
+	check(cfg.Argon2Iterations >= 1, "argon2_iterations must be at least 1 (got %d)", cfg.Argon2Iterations)
+	check(cfg.Argon2Memory >= 8*cfg.Argon2Parallelism && cfg.Argon2Memory <= 4*1024*1024,
+		"argon2_memory must be between 8 KiB per unit of parallelism and 4 GiB (got %d)", cfg.Argon2Memory)
+	check(cfg.PasswordMinLength >= 1, "password_min_length must be at least 1 (got %d)", cfg.PasswordMinLength)
+	check(cfg.PasswordMaxLength >= cfg.PasswordMinLength && cfg.PasswordMaxLength <= validator.MaxPasswordBytes,
+		"password_max_length must be between password_min_length and %d (got %d)", validator.MaxPasswordBytes, cfg.PasswordMaxLength)
+	if cfg.PasswordBreachedDir != ""{
+		info, err := os.Stat(cfg.PasswordBreachedDir)
+		check(err == nil && info.IsDir(), "password_breached_dir must be a directory (got %q)", cfg.PasswordBreachedDir)
+	}
+	check(cfg.LoginThrottleStore == "memory" || cfg.LoginThrottleStore == "database",
+		"login_throttle_store must be memory or database (got %q)", cfg.LoginThrottleStore)
+	check(cfg.LoginMaxFailures > 0, "login_max_failures must be positive (got %d)", cfg.LoginMaxFailures)
+	check(cfg.LoginMaxFailuresIP > 0, "login_max_failures_ip must be positive (got %d)", cfg.LoginMaxFailuresIP)
+	check(cfg.LoginLockout > 0, "login_lockout must be positive (got %s)", cfg.LoginLockout)
+	check(cfg.LoginLockoutMax >= cfg.LoginLockout, "login_lockout_max must not be shorter than login_lockout (got %s)", cfg.LoginLockoutMax)
+	check(cfg.LoginFailureWindow >= cfg.LoginLockoutMax, "login_failure_window must not be shorter than login_lockout_max (got %s)", cfg.LoginFailureWindow)
+	u, err := url.Parse(cfg.BaseURL)
+	check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && !strings.HasSuffix(cfg.BaseURL, "/"),
+		"base_url must be an absolute http(s) URL without a trailing slash (got %q)", cfg.BaseURL)
+	check(cfg.SecretKey == "" || len(cfg.SecretKey) >= 32, "secret_key must be at least 32 characters")
+	check(cfg.VerifyTokenLifetime > 0, "verify_token_lifetime must be positive (got %s)", cfg.VerifyTokenLifetime)
+	check(cfg.ResetTokenLifetime > 0, "reset_token_lifetime must be positive (got %s)", cfg.ResetTokenLifetime)
+	_, err = mail.ParseAddress(cfg.MailSender)
+	check(err == nil, "mail_sender is not a valid address: %v", err)
+	switch cfg.Mailer{
+	case "smtp":
+		check(cfg.SMTPHost != "", "smtp_host must not be empty when mailer is smtp")
+		check(cfg.SMTPPort > 0 && cfg.SMTPPort < 65536, "smtp_port must be a port number (got %d)", cfg.SMTPPort)
+	case "log":
+	default:
+		check(false, "mailer must be smtp or log (got %q)", cfg.Mailer)
+	}
+
+	_, err = parseTrustedProxies(cfg.TrustedProxies)
+	check(err == nil, "trusted_proxies: %v", err)
+
+	check(cfg.AuditRetention >= 0, "audit_retention must not be negative (got %s)", cfg.AuditRetention)
+	check(cfg.UploadMaxSize > 0, "upload_max_size must be positive (got %d)", cfg.UploadMaxSize)
+	// The embedded templates never change, so re-parsing them would do nothing
+	check(!cfg.Dev || cfg.UIDir != "", "dev needs ui_dir set, as the embedded templates can't be edited")
+
+	check(len(cfg.AuthBackends) > 0, "auth_backends must list at least one backend")
+	for _, backend := range cfg.AuthBackends{
+		switch backend{
+		case "local":
+		case "ldap":
+			u, err := url.Parse(cfg.LDAPURL)
+			check(err == nil && (u.Scheme == "ldap" || u.Scheme == "ldaps") && u.Host != "", "ldap_url must be an ldap:// or ldaps:// URL (got %q)", cfg.LDAPURL)
+			check(cfg.LDAPBaseDN != "", "ldap_base_dn must not be empty when auth_backends includes ldap")
+			check(strings.Count(cfg.LDAPUserFilter, "%s") == 1 && strings.Count(cfg.LDAPUserFilter, "%") == 1,
+				"ldap_user_filter must contain %%s exactly once (got %q)", cfg.LDAPUserFilter)
+			check(cfg.LDAPNameAttr != "", "ldap_name_attr must not be empty")
+			check(cfg.LDAPEmailAttr != "", "ldap_email_attr must not be empty")
+			check(cfg.LDAPTimeout > 0, "ldap_timeout must be positive (got %s)", cfg.LDAPTimeout)
+		default:
+			check(false, "auth_backends: unknown backend %q (want local or ldap)", backend)
+		}
+	}
+
+	if cfg.OIDCIssuer != ""{
+		u, err := url.Parse(cfg.OIDCIssuer)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "oidc_issuer must be an absolute http(s) URL (got %q)", cfg.OIDCIssuer)
+		check(cfg.OIDCClientID != "", "oidc_client_id must not be empty when oidc_issuer is set")
+		if cfg.OIDCRedirectURL != ""{
+			u, err := url.Parse(cfg.OIDCRedirectURL)
+			check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "oidc_redirect_url must be an absolute http(s) URL (got %q)", cfg.OIDCRedirectURL)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// show writes the effective configuration as TOML with secrets redacted.
+func (cfg *config) show(w io.Writer){
+	for _, s := range cfg.settings(){
+		value := s.String()
+		if s.secret{
+			value = redact(s.key, value)
+		}
+
+		switch s.ptr.(type){
+		case *int, *bool:
+			fmt.Fprintf(w, "%s = %s\n", s.key, value)
+		case *[]string:
+			items := []string{}
+			for _, item := range strings.Split(value, ","){
+				if item != ""{
+					items = append(items, strconv.Quote(item))
+				}
+			}
+			fmt.Fprintf(w, "%s = [%s]\n", s.key, strings.Join(items, ", "))
+		default:
+			fmt.Fprintf(w, "%s = %s\n", s.key, strconv.Quote(value))
+		}
+	}
+}
+
+// DSNs keep everything but the password, so the output is still useful for
+// spotting a wrong host or database. Other secrets are hidden entirely.
+func redact(key, value string) string{
+	if value == ""{
+		return ""
+	}
+	if key == "dsn"{
+		dsn, err := mysql.ParseDSN(value)
+		if err == nil{
+			if dsn.Passwd != ""{
+				dsn.Passwd = "REDACTED"
+			}
+			return dsn.FormatDSN()
+		}
+	}
+	return "REDACTED"
+}
+
+// Usage: snippetbox config show [flags]
+func runConfigCommand(args []string) error{
+	if len(args) == 0 || args[0] != "show"{
+		return errors.New("usage: snippetbox config show [flags]")
+	}
+
+	cfg, err := loadConfig(args[1:], os.LookupEnv)
+	if err != nil{
+		return err
+	}
+
+	cfg.show(os.Stdout)
+	return nil
+}
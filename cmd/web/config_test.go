@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+)
+
+func TestLoadConfig(t *testing.T){
+	path := filepath.Join(t.TempDir(), "snippetbox.toml")
+	file := `
+addr = ":5000"
+session_lifetime = "1h"
+bcrypt_cost = 10
+
+[tls]
+cert_file = "/etc/snippetbox/cert.pem"
+`
+	err := os.WriteFile(path, []byte(file), 0600)
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"SNIPPETBOX_CONFIG": path,
+		"SNIPPETBOX_ADDR": ":6000",
+		"SNIPPETBOX_BCRYPT_COST": "11",
+	}
+	lookupEnv := func(key string) (string, bool){
+		v, ok := env[key]
+		return v, ok
+	}
+
+	cfg, err := loadConfig([]string{"-addr", ":7000"}, lookupEnv)
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	// Flag beats environment beats file beats default
+	assert.Equal(t, cfg.Addr, ":7000")
+	assert.Equal(t, cfg.BcryptCost, 11)
+	assert.Equal(t, cfg.SessionLifetime, time.Hour)
+	assert.Equal(t, cfg.TLSCertFile, "/etc/snippetbox/cert.pem")
+	assert.Equal(t, cfg.TLSKeyFile, "./tls/key.pem")
+}
+
+func TestLoadConfigInvalid(t *testing.T){
+	noEnv := func(string) (string, bool){ return "", false }
+
+	tests := []struct{
+		name string
+		args []string
+	}{
+		{name: "Unknown password hasher", args: []string{"-password-hasher", "md5"}},
+		{name: "Bad duration", args: []string{"-read-timeout", "soon"}},
+		{name: "Negative duration", args: []string{"-read-timeout", "-1s"}},
+		{name: "Bcrypt cost too high", args: []string{"-bcrypt-cost", "99"}},
+		{name: "Empty DSN", args: []string{"-dsn", ""}},
+		{name: "OIDC without client ID", args: []string{"-oidc-issuer", "https://accounts.example.com"}},
+		{name: "Negative audit retention", args: []string{"-audit-retention", "-24h"}},
+		{name: "Zero upload size", args: []string{"-upload-max-size", "0"}},
+		{name: "Dev without ui_dir", args: []string{"-dev"}},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			_, err := loadConfig(tt.args, noEnv)
+			assert.Equal(t, err != nil, true)
+		})
+	}
+}
+
+func TestRedact(t *testing.T){
+	tests := []struct{
+		name string
+		key string
+		value string
+		want string
+	}{
+		{
+			name: "DSN with password",
+			key: "dsn",
+			value: "web:pass@tcp(db:3306)/snippetbox?parseTime=true",
+			want: "web:REDACTED@tcp(db:3306)/snippetbox?parseTime=true",
+		},
+		{
+			name: "Other secret",
+			key: "smtp_password",
+			value: "hunter2",
+			want: "REDACTED",
+		},
+		{
+			name: "Empty",
+			key: "dsn",
+			value: "",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			assert.Equal(t, redact(tt.key, tt.value), tt.want)
+		})
+	}
+}
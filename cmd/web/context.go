@@ -2,4 +2,10 @@ package main
 
 type contextKey string
 
-const isAuthenticatedContextKey = contextKey("isAuthenticated")
\ No newline at end of file
+const isAuthenticatedContextKey = contextKey("isAuthenticated")
+
+const isVerifiedContextKey = contextKey("isVerified")
+
+const userRoleContextKey = contextKey("userRole")
+
+const requestIDContextKey = contextKey("requestID")
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+)
+
+// accountExport is everything kept about a user, for them to take away.
+type accountExport struct{
+	Exported time.Time				`json:"exported"`
+	Profile exportProfile			`json:"profile"`
+	Snippets []exportSnippet		`json:"snippets"`
+	AuditLog []*models.AuditEntry	`json:"audit_log"`
+}
+
+type exportProfile struct{
+	ID int					`json:"id"`
+	Name string				`json:"name"`
+	Email string			`json:"email"`
+	Created time.Time		`json:"created"`
+	EmailVerified bool		`json:"email_verified"`
+	TOTPEnabled bool		`json:"two_factor_enabled"`
+	Role string				`json:"role"`
+}
+
+type exportSnippet struct{
+	ID int					`json:"id"`
+	Title string			`json:"title"`
+	Files []exportFile		`json:"files"`
+	Created time.Time		`json:"created"`
+	Expires time.Time		`json:"expires"`
+	ForkedFrom int			`json:"forked_from,omitempty"`
+}
+
+type exportFile struct{
+	Name string				`json:"name"`
+	Language string			`json:"language"`
+	Content string			`json:"content"`
+}
+
+// newAccountExport gathers up the user's data. It takes the models rather
+// than the application so the CLI can use it too.
+func newAccountExport(users *models.UserModel, snippets *models.SnippetModel, auditLog *models.AuditModel, userID int) (*accountExport, error){
+	user, err := users.Get(userID)
+	if err != nil{
+		return nil, err
+	}
+
+	e := &accountExport{
+		Exported: time.Now().UTC(),
+		Profile: exportProfile{
+			ID: user.ID,
+			Name: user.Name,
+			Email: user.Email,
+			Created: user.Created,
+			EmailVerified: user.EmailVerified,
+			TOTPEnabled: user.TOTPEnabled,
+			Role: user.Role,
+		},
+		Snippets: []exportSnippet{},
+		AuditLog: []*models.AuditEntry{},
+	}
+
+	owned, err := snippets.ForUser(userID)
+	if err != nil{
+		return nil, err
+	}
+	for _, s := range owned{
+		es := exportSnippet{ID: s.ID, Title: s.Title, Files: []exportFile{}, Created: s.Created, Expires: s.Expires, ForkedFrom: s.ParentID}
+		for _, f := range s.Files{
+			es.Files = append(es.Files, exportFile{Name: f.Name, Language: f.Language, Content: f.Content})
+		}
+		e.Snippets = append(e.Snippets, es)
+	}
+
+	err = auditLog.Each(models.AuditFilter{ActorID: userID}, func(entry *models.AuditEntry) error{
+		e.AuditLog = append(e.AuditLog, entry)
+		return nil
+	})
+	if err != nil{
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (e *accountExport) writeJSON(w io.Writer) error{
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(e)
+}
+
+// writeZIP writes the same data as writeJSON split into files, with each
+// snippet's files also in a directory of their own.
+func (e *accountExport) writeZIP(w io.Writer) error{
+	zw := zip.NewWriter(w)
+
+	files := []struct{
+		name string
+		value any
+	}{
+		{"profile.json", e.Profile},
+		{"snippets.json", e.Snippets},
+		{"audit_log.json", e.AuditLog},
+	}
+	for _, file := range files{
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: e.Exported})
+		if err != nil{
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(file.value)
+		if err != nil{
+			return err
+		}
+	}
+
+	for _, s := range e.Snippets{
+		dir := snippetDirName(s.ID, s.Title)
+		for _, sf := range s.Files{
+			f, err := zw.CreateHeader(&zip.FileHeader{Name: dir + "/" + sf.Name, Method: zip.Deflate, Modified: s.Created})
+			if err != nil{
+				return err
+			}
+			_, err = io.WriteString(f, sf.Content)
+			if err != nil{
+				return err
+			}
+		}
+	}
+
+	return zw.Close()
+}
+
+// fileName is what to call an export, e.g. snippetbox-export-2024-03-01.zip
+func (e *accountExport) fileName(ext string) string{
+	return fmt.Sprintf("snippetbox-export-%s.%s", e.Exported.Format("2006-01-02"), ext)
+}
+
+var unsafeFileNameRX = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// snippetDirName is a safe directory for a snippet's files in an archive,
+// e.g. snippets/12-hello-world
+func snippetDirName(id int, title string) string{
+	slug := strings.Trim(unsafeFileNameRX.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(slug) > 50{
+		slug = strings.TrimRight(slug[:50], "-")
+	}
+	if slug == ""{
+		return fmt.Sprintf("snippets/%d", id)
+	}
+	return fmt.Sprintf("snippets/%d-%s", id, slug)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+)
+
+func TestSnippetDirName(t *testing.T){
+	tests := []struct{
+		name string
+		id int
+		title string
+		want string
+	}{
+		{name: "Plain", id: 12, title: "Hello world", want: "snippets/12-hello-world"},
+		{name: "Path tricks", id: 3, title: "../../etc/passwd", want: "snippets/3-etc-passwd"},
+		{name: "Nothing left", id: 7, title: "!!!", want: "snippets/7"},
+		{name: "Long", id: 1, title: "a very long title that goes on and on and on and on and on", want: "snippets/1-a-very-long-title-that-goes-on-and-on-and-on-and-o"},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			assert.Equal(t, snippetDirName(tt.id, tt.title), tt.want)
+		})
+	}
+}
+
+func TestAccountExportZIP(t *testing.T){
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	e := &accountExport{
+		Exported: created,
+		Profile: exportProfile{ID: 1, Name: "Alice", Email: "alice@example.com"},
+		Snippets: []exportSnippet{{ID: 4, Title: "An old silent pond", Files: []exportFile{{Name: "haiku.txt", Content: "An old silent pond..."}}, Created: created}},
+	}
+
+	var buf bytes.Buffer
+	err := e.writeZIP(&buf)
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	files := map[string]string{}
+	for _, f := range zr.File{
+		rc, err := f.Open()
+		if err != nil{
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil{
+			t.Fatal(err)
+		}
+		files[f.Name] = string(b)
+	}
+
+	assert.Equal(t, len(files), 4)
+	assert.Equal(t, files["snippets/4-an-old-silent-pond/haiku.txt"], "An old silent pond...")
+
+	var profile exportProfile
+	err = json.Unmarshal([]byte(files["profile.json"]), &profile)
+	if err != nil{
+		t.Fatal(err)
+	}
+	assert.Equal(t, profile.Email, "alice@example.com")
+	assert.Equal(t, e.fileName("zip"), "snippetbox-export-2024-03-01.zip")
+}
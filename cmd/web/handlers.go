@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/AVSanjay-12/snippetbox/internal/models"
 	"github.com/AVSanjay-12/snippetbox/internal/validator"
@@ -48,24 +50,68 @@ func (app *application) snippetView(w http.ResponseWriter, r *http.Request){
 
 	data := app.newTemplateData(r)
 	data.Snippet = snippet	
+	data.IsOwner = snippet.UserID != 0 && snippet.UserID == app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	// Lineage only links to snippets that can still be viewed
+	if snippet.ParentID != 0{
+		data.Parent, err = app.snippets.Get(snippet.ParentID)
+		if err != nil && !errors.Is(err, models.ErrNoRecord){
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	data.Forks, err = app.snippets.Forks(snippet.ID)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
 
 	// helper
 	app.render(w, http.StatusOK, "view.html", data)
 }
 
+// snippetCreate shows an empty form, or with ?fork=N one filled in with a
+// copy of snippet N.
 func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request){
-	data := app.newTemplateData(r)
-	data.Form = snippetCreateForm{
+	form := snippetCreateForm{
+		snippetFilesForm: newSnippetFilesForm("", nil),
 		Expires: 365,
 	}
+
+	if fork := r.URL.Query().Get("fork"); fork != ""{
+		id, err := strconv.Atoi(fork)
+		if err != nil || id < 1{
+			app.notFound(w)
+			return
+		}
+
+		// Only what can be viewed can be forked
+		source, err := app.snippets.Get(id)
+		if err != nil{
+			if errors.Is(err, models.ErrNoRecord){
+				app.notFound(w)
+			} else{
+				app.serverError(w, err)
+			}
+			return
+		}
+
+		form.snippetFilesForm = newSnippetFilesForm(source.Title, source.Files)
+		form.Fork = source.ID
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = form
 	app.render(w, http.StatusOK, "create.html", data)
 }
 
 // To repopulate fields during validation error
 type snippetCreateForm struct{
-	Title string	`form:"title"`
-	Content string	`form:"content"`
+	snippetFilesForm
 	Expires int		`form:"expires"`
+	// The snippet being forked, if any
+	Fork int		`form:"fork"`
 	validator.Validator `form:"-"`
 }
 
@@ -76,15 +122,42 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 
 	err := app.decodePostForm(r, &form)
 	if err != nil {
-		app.clientError(w, http.StatusBadRequest)
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError){
+			app.clientError(w, http.StatusRequestEntityTooLarge)
+		} else{
+			app.clientError(w, http.StatusBadRequest)
+		}
+		return
+	}
+
+	// Uploaded files are added to any typed in
+	err = form.readUploads(r, &form.Validator)
+	if err != nil{
+		app.serverError(w, err)
 		return
 	}
 
-	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be empty")
-	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
-	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be empty")
+	if form.changeFiles(){
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusOK, "create.html", data)
+		return
+	}
+
+	form.normalize()
+	form.check(&form.Validator)
 	form.CheckField(validator.PermittedInt(form.Expires, 1, 7, 365), "expires", "This field must equal 1, 7 or 365")
 
+	// The source may have expired or been deleted since the form was shown
+	if form.Fork != 0{
+		_, err = app.snippets.Get(form.Fork)
+		if err != nil && !errors.Is(err, models.ErrNoRecord){
+			app.serverError(w, err)
+			return
+		}
+		form.CheckField(err == nil, "fork", "The snippet you're forking is no longer available")
+	}
 
 	if !form.Valid(){
 		data := app.newTemplateData(r)
@@ -93,17 +166,165 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	id, err := app.snippets.Insert(form.Title, form.Content, form.Expires)
+	id, err := app.snippets.Insert(form.Title, form.snippetFiles(), form.Expires, app.sessionManager.GetInt(r.Context(), "authenticatedUserID"), form.Fork)
 	if err != nil{
 		app.serverError(w, err)
 		return
 	}
+	detail := map[string]any{"snippet_id": id, "title": form.Title}
+	if form.Fork != 0{
+		detail["forked_from"] = form.Fork
+	}
+	app.audit(r, models.AuditSnippetCreate, detail)
 
 	app.sessionManager.Put(r.Context(), "flash", "Snippet created successfully!")
 
 	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
 }
 
+type snippetEditForm struct{
+	snippetFilesForm
+	validator.Validator `form:"-"`
+}
+
+// ownedSnippet returns the snippet named in the URL if the signed-in user
+// owns it. Otherwise it responds and returns false.
+func (app *application) ownedSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool){
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1{
+		app.notFound(w)
+		return nil, false
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil{
+		if errors.Is(err, models.ErrNoRecord){
+			app.notFound(w)
+		} else{
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	if snippet.UserID == 0 || snippet.UserID != app.sessionManager.GetInt(r.Context(), "authenticatedUserID"){
+		app.clientError(w, http.StatusForbidden)
+		return nil, false
+	}
+	return snippet, true
+}
+
+func (app *application) snippetEdit(w http.ResponseWriter, r *http.Request){
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok{
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Form = snippetEditForm{
+		snippetFilesForm: newSnippetFilesForm(snippet.Title, snippet.Files),
+	}
+	app.render(w, http.StatusOK, "edit.html", data)
+}
+
+// snippetEditPost saves the changes as a new revision, keeping the old ones
+// in the history.
+func (app *application) snippetEditPost(w http.ResponseWriter, r *http.Request){
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok{
+		return
+	}
+
+	var form snippetEditForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil{
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if form.changeFiles(){
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, http.StatusOK, "edit.html", data)
+		return
+	}
+
+	form.normalize()
+	form.check(&form.Validator)
+
+	if !form.Valid(){
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "edit.html", data)
+		return
+	}
+
+	changed, err := app.snippets.Update(snippet.ID, form.Title, form.snippetFiles(), snippet.UserID)
+	if err != nil{
+		if errors.Is(err, models.ErrNoRecord){
+			app.notFound(w)
+		} else{
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	if changed{
+		app.audit(r, models.AuditSnippetEdit, map[string]any{"snippet_id": snippet.ID, "title": form.Title})
+		app.sessionManager.Put(r.Context(), "flash", "Snippet updated.")
+	} else{
+		app.sessionManager.Put(r.Context(), "flash", "There were no changes to save.")
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", snippet.ID), http.StatusSeeOther)
+}
+
+// snippetDeletePost deletes a snippet for its owner, or for an admin.
+func (app *application) snippetDeletePost(w http.ResponseWriter, r *http.Request){
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1{
+		app.notFound(w)
+		return
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil{
+		if errors.Is(err, models.ErrNoRecord){
+			app.notFound(w)
+		} else{
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	userID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	if !app.isAdmin(r) && (snippet.UserID == 0 || snippet.UserID != userID){
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	err = app.snippets.Delete(id)
+	if err != nil && !errors.Is(err, models.ErrNoRecord){
+		app.serverError(w, err)
+		return
+	}
+	action := models.AuditSnippetDelete
+	if snippet.UserID != userID{
+		action = models.AuditAdminSnippetDelete
+	}
+	app.audit(r, action, map[string]any{"snippet_id": id, "title": snippet.Title, "owner_id": snippet.UserID})
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet deleted.")
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
 type userSignupForm struct{
 	Name string			`form:"name"`
 	Email string		`form:"email"`
@@ -123,13 +344,19 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request){
 	err := app.decodePostForm(r, &form)
 	if err != nil{
 		app.clientError(w, http.StatusBadRequest)
+		return
 	}
 
 	form.CheckField(validator.NotBlank(form.Name), "name", "This field should not be empty")
 	form.CheckField(validator.NotBlank(form.Email), "email", "This field should not be empty")
 	form.CheckField(validator.Matches(form.Email, validator.EmailRX), "email", "Please enter a valid email")
 	form.CheckField(validator.NotBlank(form.Password), "password", "This field should not be empty")
-	form.CheckField(validator.MinChars(form.Password, 8), "password", "Password should be at least 8 characters")
+
+	err = app.checkPassword(&form.Validator, "password", form.Password, form.Name, form.Email)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
 
 	if !form.Valid(){
 		data := app.newTemplateData(r)
@@ -138,7 +365,7 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request){
 		return
 	}
 
-	err = app.users.Insert(form.Name, form.Email, form.Password)
+	id, err := app.users.Insert(form.Name, form.Email, form.Password)
 	if err != nil{
 		if errors.Is(err, models.ErrDuplicateEmail){
 			form.AddFieldErrors("email", "User already exists")
@@ -151,7 +378,10 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request){
 		return
 	}
 
-	app.sessionManager.Put(r.Context(), "flash", "Your signup was successful. Please log in.")
+	app.auditAs(r, id, models.AuditSignup, map[string]any{"email": form.Email})
+	app.sendVerificationEmail(&models.User{ID: id, Name: form.Name, Email: form.Email})
+
+	app.sessionManager.Put(r.Context(), "flash", "Your signup was successful. We've emailed you a link to confirm your address. Please log in.")
 
 	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
 }
@@ -159,6 +389,7 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request){
 type userLoginForm struct{
 	Email		string	`form:"email"`
 	Password	string	`form:"password"`
+	RememberMe	bool	`form:"remember_me"`
 	validator.Validator	`form:"-"`
 }
 
@@ -181,9 +412,44 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request){
 	form.CheckField(validator.Matches(form.Email, validator.EmailRX), "email", "This field must be a valid email address")
 	form.CheckField(validator.NotBlank(form.Password), "password", "This field cannot be blank")
 
-	id, err := app.users.Authenticate(form.Email, form.Password)
+	if !form.Valid(){
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "login.html", data)
+		return
+	}
+
+	ip := app.clientIP(r)
+
+	// Locked accounts and IPs are refused before the password is checked. The
+	// lock is keyed on the submitted email, so it says nothing about whether
+	// the account exists.
+	locked, err := app.loginThrottle.check(form.Email, ip)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	if locked{
+		app.auditAs(r, 0, models.AuditLoginFailed, map[string]any{"email": form.Email, "reason": "locked"})
+
+		form.AddNonFieldErrors("Too many failed login attempts. Please try again later.")
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusTooManyRequests, "login.html", data)
+		return
+	}
+
+	id, err := app.authenticator.Authenticate(form.Email, form.Password)
 	if err != nil{
 		if errors.Is(err, models.ErrInvalidCredentials){
+			app.auditAs(r, 0, models.AuditLoginFailed, map[string]any{"email": form.Email, "reason": "invalid_credentials"})
+
+			err = app.loginThrottle.fail(form.Email, ip)
+			if err != nil{
+				app.serverError(w, err)
+				return
+			}
+
 			form.AddNonFieldErrors("Invalid Email or Password")
 			data := app.newTemplateData(r)
 			data.Form = form
@@ -194,30 +460,451 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request){
 		return
 	}
 
-	err = app.sessionManager.RenewToken(r.Context())
+	failures, err := app.loginThrottle.succeed(form.Email)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	user, err := app.users.Get(id)
 	if err != nil{
 		app.serverError(w, err)
 		return
 	}
 
-	// Add the ID of the current user to the session, so that they are now
-	// 'logged in'.
-	app.sessionManager.Put(r.Context(), "authenticatedUserID", id)
+	if user.Disabled{
+		app.auditAs(r, id, models.AuditLoginFailed, map[string]any{"email": form.Email, "reason": "disabled"})
+
+		form.AddNonFieldErrors("This account has been disabled.")
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusForbidden, "login.html", data)
+		return
+	}
+
+	// With two-factor authentication on, the password only gets the user as
+	// far as the code form - authenticatedUserID isn't set until that passes.
+	if user.TOTPEnabled{
+		err = app.beginTOTPLogin(r, id, form.RememberMe)
+		if err != nil{
+			app.serverError(w, err)
+			return
+		}
+		app.flashLoginFailures(r, failures)
+
+		http.Redirect(w, r, "/user/login/totp", http.StatusSeeOther)
+		return
+	}
+
+	err = app.logIn(r, id, "password")
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	if form.RememberMe{
+		err = app.remember(w, r, id)
+		if err != nil{
+			app.serverError(w, err)
+			return
+		}
+	}
+	app.flashLoginFailures(r, failures)
 
 	// Redirect the user to the create snippet page.
 	http.Redirect(w, r, "/snippet/create", http.StatusSeeOther)
 
 }
 
+func (app *application) flashLoginFailures(r *http.Request, failures int){
+	if failures > 0{
+		app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("There were %d failed login attempts on your account since you last logged in.", failures))
+	}
+}
+
 func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request){
-	err := app.sessionManager.RenewToken(r.Context())
+	app.audit(r, models.AuditLogout, nil)
+
+	userID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	err := app.sessions.Delete(app.sessionManager.GetString(r.Context(), "sessionID"), userID)
+	if err != nil && !errors.Is(err, models.ErrNoRecord){
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.sessionManager.RenewToken(r.Context())
 	if err != nil{
 		app.serverError(w, err)
 		return
 	}
 
 	app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+	app.sessionManager.Remove(r.Context(), "sessionID")
+	app.forgetRememberCookie(w)
 	app.sessionManager.Put(r.Context(), "flash", "You have been Logged out")
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
-}
\ No newline at end of file
+}
+
+// sendVerificationEmail mails a signed link that confirms the user's address.
+// The link carries the address it was sent to, so it stops working if the
+// address changes.
+func (app *application) sendVerificationEmail(user *models.User){
+	expires := time.Now().Add(app.cfg.VerifyTokenLifetime)
+	token := signToken(app.secretKey, "verify-email", expires, strconv.Itoa(user.ID), user.Email)
+
+	data := map[string]any{
+		"Name": user.Name,
+		"URL": app.cfg.BaseURL + "/user/verify?token=" + url.QueryEscape(token),
+		"Expires": humanDate(expires),
+	}
+
+	app.background(func(){
+		err := app.mailer.Send(user.Email, "user_verify.tmpl", data)
+		if err != nil{
+			app.errorLog.Printf("sending verification email to user %d: %s", user.ID, err)
+		}
+	})
+}
+
+func (app *application) userVerify(w http.ResponseWriter, r *http.Request){
+	fields, err := verifyToken(app.secretKey, "verify-email", r.URL.Query().Get("token"))
+	if err != nil || len(fields) != 2{
+		app.sessionManager.Put(r.Context(), "flash", "That confirmation link is invalid or has expired.")
+		http.Redirect(w, r, "/user/verify/pending", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(fields[0])
+	if err != nil{
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	user, err := app.users.Get(id)
+	if err != nil{
+		if errors.Is(err, models.ErrNoRecord){
+			app.notFound(w)
+		} else{
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	if user.Email != fields[1]{
+		app.sessionManager.Put(r.Context(), "flash", "That confirmation link is invalid or has expired.")
+		http.Redirect(w, r, "/user/verify/pending", http.StatusSeeOther)
+		return
+	}
+
+	if !user.EmailVerified{
+		err = app.users.SetEmailVerified(id)
+		if err != nil{
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Thanks, your email address is confirmed.")
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (app *application) userVerifyPending(w http.ResponseWriter, r *http.Request){
+	if app.isVerified(r){
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	app.render(w, http.StatusOK, "verify.html", data)
+}
+
+func (app *application) userVerifyResendPost(w http.ResponseWriter, r *http.Request){
+	user, err := app.users.Get(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	if !user.EmailVerified{
+		app.sendVerificationEmail(user)
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("We've sent a new confirmation link to %s.", user.Email))
+	http.Redirect(w, r, "/user/verify/pending", http.StatusSeeOther)
+}
+
+type userForgotPasswordForm struct{
+	Email string		`form:"email"`
+	validator.Validator	`form:"-"`
+}
+
+func (app *application) userForgotPassword(w http.ResponseWriter, r *http.Request){
+	data := app.newTemplateData(r)
+	data.Form = userForgotPasswordForm{}
+	app.render(w, http.StatusOK, "forgot.html", data)
+}
+
+func (app *application) userForgotPasswordPost(w http.ResponseWriter, r *http.Request){
+	var form userForgotPasswordForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil{
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Email), "email", "This field cannot be blank")
+	form.CheckField(validator.Matches(form.Email, validator.EmailRX), "email", "This field must be a valid email address")
+
+	if !form.Valid(){
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "forgot.html", data)
+		return
+	}
+
+	user, err := app.users.GetByEmail(form.Email)
+	if err != nil && !errors.Is(err, models.ErrNoRecord){
+		app.serverError(w, err)
+		return
+	}
+
+	if user != nil{
+		token, err := app.passwordResets.New(user.ID, app.cfg.ResetTokenLifetime)
+		if err != nil{
+			app.serverError(w, err)
+			return
+		}
+
+		data := map[string]any{
+			"Name": user.Name,
+			"URL": app.cfg.BaseURL + "/user/password/reset?token=" + url.QueryEscape(token),
+			"Expires": humanDate(time.Now().Add(app.cfg.ResetTokenLifetime)),
+		}
+
+		app.background(func(){
+			err := app.mailer.Send(user.Email, "password_reset.tmpl", data)
+			if err != nil{
+				app.errorLog.Printf("sending password reset email to user %d: %s", user.ID, err)
+			}
+		})
+	}
+
+	// The same answer whether or not the address is registered
+	app.sessionManager.Put(r.Context(), "flash", "If there's an account for that address, we've emailed it a link to reset the password.")
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
+
+type userResetPasswordForm struct{
+	Token string		`form:"token"`
+	Password string		`form:"password"`
+	validator.Validator	`form:"-"`
+}
+
+func (app *application) userResetPassword(w http.ResponseWriter, r *http.Request){
+	token := r.URL.Query().Get("token")
+
+	_, err := app.passwordResets.UserID(token)
+	if err != nil{
+		if errors.Is(err, models.ErrNoRecord){
+			app.sessionManager.Put(r.Context(), "flash", "That reset link is invalid or has expired. Please ask for a new one.")
+			http.Redirect(w, r, "/user/password/forgot", http.StatusSeeOther)
+		} else{
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = userResetPasswordForm{Token: token}
+	app.render(w, http.StatusOK, "reset.html", data)
+}
+
+func (app *application) userResetPasswordPost(w http.ResponseWriter, r *http.Request){
+	var form userResetPasswordForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil{
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	id, err := app.passwordResets.UserID(form.Token)
+	if err != nil{
+		if errors.Is(err, models.ErrNoRecord){
+			app.sessionManager.Put(r.Context(), "flash", "That reset link is invalid or has expired. Please ask for a new one.")
+			http.Redirect(w, r, "/user/password/forgot", http.StatusSeeOther)
+		} else{
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	user, err := app.users.Get(id)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Password), "password", "This field should not be empty")
+
+	err = app.checkPassword(&form.Validator, "password", form.Password, user.Name, user.Email)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	if !form.Valid(){
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "reset.html", data)
+		return
+	}
+
+	// Only now is the token used up, and only one request can do that
+	_, err = app.passwordResets.Consume(form.Token)
+	if err != nil{
+		if errors.Is(err, models.ErrNoRecord){
+			app.sessionManager.Put(r.Context(), "flash", "That reset link is invalid or has expired. Please ask for a new one.")
+			http.Redirect(w, r, "/user/password/forgot", http.StatusSeeOther)
+		} else{
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	err = app.users.UpdatePassword(id, form.Password)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	app.auditAs(r, id, models.AuditPasswordReset, nil)
+
+	// Following the emailed link proves the address is theirs
+	err = app.users.SetEmailVerified(id)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.revokeSessions(id, "")
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	// The request's own session may be one of them, or someone else's
+	err = app.sessionManager.RenewToken(r.Context())
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+	app.sessionManager.Remove(r.Context(), "sessionID")
+
+	app.sessionManager.Put(r.Context(), "flash", "Your password has been reset. Please log in.")
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
+
+func (app *application) accountView(w http.ResponseWriter, r *http.Request){
+	user, err := app.users.Get(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil{
+		if errors.Is(err, models.ErrNoRecord){
+			http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		} else{
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.User = user
+	app.render(w, http.StatusOK, "account.html", data)
+}
+
+type accountPasswordUpdateForm struct{
+	CurrentPassword string			`form:"currentPassword"`
+	NewPassword string				`form:"newPassword"`
+	NewPasswordConfirmation string	`form:"newPasswordConfirmation"`
+	validator.Validator				`form:"-"`
+}
+
+func (app *application) accountPasswordUpdate(w http.ResponseWriter, r *http.Request){
+	data := app.newTemplateData(r)
+	data.Form = accountPasswordUpdateForm{}
+	app.render(w, http.StatusOK, "password.html", data)
+}
+
+func (app *application) accountPasswordUpdatePost(w http.ResponseWriter, r *http.Request){
+	var form accountPasswordUpdateForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil{
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	user, err := app.users.Get(id)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.CurrentPassword), "currentPassword", "This field cannot be blank")
+	form.CheckField(validator.NotBlank(form.NewPassword), "newPassword", "This field cannot be blank")
+	form.CheckField(validator.NotBlank(form.NewPasswordConfirmation), "newPasswordConfirmation", "This field cannot be blank")
+	form.CheckField(form.NewPassword == form.NewPasswordConfirmation, "newPasswordConfirmation", "Passwords do not match")
+
+	err = app.checkPassword(&form.Validator, "newPassword", form.NewPassword, user.Name, user.Email)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	if !form.Valid(){
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "password.html", data)
+		return
+	}
+
+	err = app.users.PasswordUpdate(id, form.CurrentPassword, form.NewPassword)
+	if err != nil{
+		if errors.Is(err, models.ErrInvalidCredentials){
+			form.AddFieldErrors("currentPassword", "Current password is incorrect")
+			data := app.newTemplateData(r)
+			data.Form = form
+			app.render(w, http.StatusUnprocessableEntity, "password.html", data)
+		} else{
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.audit(r, models.AuditPasswordChange, nil)
+
+	// Anyone signed in with the old password is signed out, except here
+	err = app.revokeSessions(id, app.sessionManager.GetString(r.Context(), "sessionID"))
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	// A privilege change, so issue a new session token
+	err = app.sessionManager.RenewToken(r.Context())
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	err = app.sessions.SetToken(app.sessionManager.GetString(r.Context(), "sessionID"), app.sessionManager.Token(r.Context()))
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Your password has been updated!")
+	http.Redirect(w, r, "/account/view", http.StatusSeeOther)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+	"github.com/AVSanjay-12/snippetbox/internal/validator"
+)
+
+// accountExport sends the user everything kept about them, as JSON or, with
+// ?format=zip, a ZIP archive.
+func (app *application) accountExport(w http.ResponseWriter, r *http.Request){
+	format := r.URL.Query().Get("format")
+	if format == ""{
+		format = "json"
+	}
+	if format != "json" && format != "zip"{
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	userID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	export, err := newAccountExport(app.users, app.snippets, app.auditLog, userID)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	// Built in memory first, so a failure part way through is a proper error
+	// rather than a truncated download
+	buf := new(bytes.Buffer)
+	contentType := "application/json"
+	if format == "zip"{
+		contentType = "application/zip"
+		err = export.writeZIP(buf)
+	} else{
+		err = export.writeJSON(buf)
+	}
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	app.audit(r, models.AuditAccountExport, map[string]any{"format": format})
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.fileName(format)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(buf.Bytes())
+}
+
+type accountDeleteForm struct{
+	Password string		`form:"password"`
+	Snippets string		`form:"snippets"`
+	validator.Validator	`form:"-"`
+}
+
+func (app *application) accountDelete(w http.ResponseWriter, r *http.Request){
+	data, err := app.accountDeleteData(r, accountDeleteForm{Snippets: "delete"})
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	app.render(w, http.StatusOK, "account_delete.html", data)
+}
+
+// accountDeleteData is the delete page's data. Accounts made through single
+// sign-on have a random password, so the page says how to set one first.
+func (app *application) accountDeleteData(r *http.Request, form accountDeleteForm) (*templateData, error){
+	linked, err := app.identities.Linked(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil{
+		return nil, err
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = form
+	data.SingleSignOn = linked
+	return data, nil
+}
+
+// accountDeletePost deletes the account once the user has confirmed their
+// password, with their snippets either deleted or left without an owner.
+func (app *application) accountDeletePost(w http.ResponseWriter, r *http.Request){
+	var form accountDeleteForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil{
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	user, err := app.users.Get(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Password), "password", "This field cannot be blank")
+	form.CheckField(form.Snippets == "delete" || form.Snippets == "anonymize", "snippets", "Choose what happens to your snippets")
+
+	if form.Valid(){
+		id, err := app.authenticator.Authenticate(user.Email, form.Password)
+		if err != nil && !errors.Is(err, models.ErrInvalidCredentials){
+			app.serverError(w, err)
+			return
+		}
+		form.CheckField(err == nil && id == user.ID, "password", "Password is incorrect")
+	}
+
+	if !form.Valid(){
+		data, err := app.accountDeleteData(r, form)
+		if err != nil{
+			app.serverError(w, err)
+			return
+		}
+		app.render(w, http.StatusUnprocessableEntity, "account_delete.html", data)
+		return
+	}
+
+	// Their session records go with the account, so find the sessions to
+	// clear out of the store first
+	tokens, err := app.sessions.Tokens(user.ID, "")
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.users.Delete(user.ID, form.Snippets == "anonymize")
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	app.audit(r, models.AuditAccountDelete, map[string]any{"user_id": user.ID, "snippets": form.Snippets})
+
+	err = app.destroySessions(tokens)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.sessionManager.RenewToken(r.Context())
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+	app.sessionManager.Remove(r.Context(), "sessionID")
+	app.forgetRememberCookie(w)
+
+	app.sessionManager.Put(r.Context(), "flash", "Your account has been deleted.")
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"database/sql/driver"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+	"github.com/AVSanjay-12/snippetbox/ui"
+	"github.com/alexedwards/scs/v2"
+	"github.com/alexedwards/scs/v2/memstore"
+)
+
+func TestAccountDeleteSingleSignOn(t *testing.T){
+	staticFS, err := fs.Sub(ui.Files, "static")
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	cache, err := newTemplateCache(ui.Files, newStaticFiles(staticFS, true))
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	tests := []struct{
+		name string
+		linked bool
+		wantResetLink bool
+	}{
+		{name: "Local account", linked: false},
+		{name: "Single sign-on account", linked: true, wantResetLink: true},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			db, _ := newStubDB(map[string][][]driver.Value{
+				"FROM user_identities WHERE user_id = ?": {{tt.linked}},
+			})
+			defer db.Close()
+
+			sessionManager := scs.New()
+			sessionManager.Store = memstore.New()
+			app := &application{
+				errorLog: log.New(io.Discard, "", 0),
+				cfg: defaultConfig(),
+				identities: &models.IdentityModel{DB: db},
+				sessionManager: sessionManager,
+			}
+			app.templateCache.Store(&cache)
+
+			token := newSession(t, sessionManager, map[string]any{"authenticatedUserID": 1})
+
+			r, err := http.NewRequest(http.MethodGet, "/account/delete", nil)
+			if err != nil{
+				t.Fatal(err)
+			}
+			r.AddCookie(&http.Cookie{Name: sessionManager.Cookie.Name, Value: token})
+
+			rr := httptest.NewRecorder()
+			sessionManager.LoadAndSave(http.HandlerFunc(app.accountDelete)).ServeHTTP(rr, r)
+
+			assert.Equal(t, rr.Code, http.StatusOK)
+			assert.Equal(t, strings.Contains(rr.Body.String(), `<a href="/user/password/forgot">Set one</a>`), tt.wantResetLink)
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+	"github.com/AVSanjay-12/snippetbox/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Days of signups shown on the dashboard
+const statsDays = 30
+
+func (app *application) adminDashboard(w http.ResponseWriter, r *http.Request){
+	stats, err := app.stats.Get(statsDays)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Stats = stats
+	app.render(w, http.StatusOK, "admin.html", data)
+}
+
+func (app *application) adminUsers(w http.ResponseWriter, r *http.Request){
+	page := newPagination(r.URL)
+
+	users, total, err := app.users.Search(page.Query, pageSize, page.Offset())
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	page.Total = total
+
+	data := app.newTemplateData(r)
+	data.Users = users
+	data.Page = page
+	app.render(w, http.StatusOK, "admin_users.html", data)
+}
+
+type adminUserRoleForm struct{
+	Role string	`form:"role"`
+	Next string	`form:"next"`
+}
+
+func (app *application) adminUserRolePost(w http.ResponseWriter, r *http.Request){
+	var form adminUserRoleForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil || !models.ValidRole(form.Role){
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	user, ok := app.adminTargetUser(w, r, form.Next)
+	if !ok{
+		return
+	}
+
+	err = app.users.SetRole(user.ID, form.Role)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	app.audit(r, models.AuditAdminUserRole, map[string]any{"user_id": user.ID, "email": user.Email, "from": user.Role, "to": form.Role})
+
+	app.sessionManager.Put(r.Context(), "flash", "Role updated.")
+	http.Redirect(w, r, adminNext(form.Next, "/admin/users"), http.StatusSeeOther)
+}
+
+type adminNextForm struct{
+	Next string	`form:"next"`
+}
+
+// adminUserDisablePost disables an account and signs it out everywhere.
+func (app *application) adminUserDisablePost(w http.ResponseWriter, r *http.Request){
+	app.adminSetUserDisabled(w, r, true)
+}
+
+func (app *application) adminUserEnablePost(w http.ResponseWriter, r *http.Request){
+	app.adminSetUserDisabled(w, r, false)
+}
+
+func (app *application) adminSetUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool){
+	var form adminNextForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil{
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	user, ok := app.adminTargetUser(w, r, form.Next)
+	if !ok{
+		return
+	}
+
+	err = app.users.SetDisabled(user.ID, disabled)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	action, flash := models.AuditAdminUserEnable, "Account enabled."
+	if disabled{
+		action, flash = models.AuditAdminUserDisable, "Account disabled."
+
+		err = app.revokeSessions(user.ID, "")
+		if err != nil{
+			app.serverError(w, err)
+			return
+		}
+	}
+	app.audit(r, action, map[string]any{"user_id": user.ID, "email": user.Email})
+
+	app.sessionManager.Put(r.Context(), "flash", flash)
+	http.Redirect(w, r, adminNext(form.Next, "/admin/users"), http.StatusSeeOther)
+}
+
+// adminTargetUser loads the user named in the URL for an admin action. Admins
+// can't act on their own account, so they can't lock themselves out.
+func (app *application) adminTargetUser(w http.ResponseWriter, r *http.Request, next string) (*models.User, bool){
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1{
+		app.notFound(w)
+		return nil, false
+	}
+
+	if id == app.sessionManager.GetInt(r.Context(), "authenticatedUserID"){
+		app.sessionManager.Put(r.Context(), "flash", "You can't change your own account from here.")
+		http.Redirect(w, r, adminNext(next, "/admin/users"), http.StatusSeeOther)
+		return nil, false
+	}
+
+	user, err := app.users.Get(id)
+	if err != nil{
+		if errors.Is(err, models.ErrNoRecord){
+			app.notFound(w)
+		} else{
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	return user, true
+}
+
+func (app *application) adminSnippets(w http.ResponseWriter, r *http.Request){
+	page := newPagination(r.URL)
+
+	snippets, total, err := app.snippets.Search(page.Query, pageSize, page.Offset())
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	page.Total = total
+
+	data := app.newTemplateData(r)
+	data.Snippets = snippets
+	data.Page = page
+	app.render(w, http.StatusOK, "admin_snippets.html", data)
+}
+
+func (app *application) adminSnippetExpirePost(w http.ResponseWriter, r *http.Request){
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1{
+		app.notFound(w)
+		return
+	}
+
+	var form adminNextForm
+
+	err = app.decodePostForm(r, &form)
+	if err != nil{
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = app.snippets.Expire(id)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	app.audit(r, models.AuditAdminSnippetExpire, map[string]any{"snippet_id": id})
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet expired.")
+	http.Redirect(w, r, adminNext(form.Next, "/admin/snippets"), http.StatusSeeOther)
+}
+
+type adminAuditForm struct{
+	User string	`form:"user"`
+	From string	`form:"from"`
+	To string	`form:"to"`
+	validator.Validator	`form:"-"`
+}
+
+// adminAudit lists audit entries, newest first, optionally for one user and
+// between two dates.
+func (app *application) adminAudit(w http.ResponseWriter, r *http.Request){
+	query := r.URL.Query()
+	form := adminAuditForm{User: query.Get("user"), From: query.Get("from"), To: query.Get("to")}
+
+	var filter models.AuditFilter
+	var err error
+
+	filter.Since, err = parseAuditTime(form.From, false)
+	form.CheckField(err == nil, "from", "Enter a date as YYYY-MM-DD")
+	filter.Until, err = parseAuditTime(form.To, true)
+	form.CheckField(err == nil, "to", "Enter a date as YYYY-MM-DD")
+
+	if form.User != ""{
+		user, err := app.users.GetByEmail(form.User)
+		if err != nil && !errors.Is(err, models.ErrNoRecord){
+			app.serverError(w, err)
+			return
+		}
+		form.CheckField(err == nil, "user", "No user has this email")
+		if user != nil{
+			filter.ActorID = user.ID
+		}
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = form
+	data.Page = newPagination(r.URL)
+
+	if !form.Valid(){
+		app.render(w, http.StatusUnprocessableEntity, "admin_audit.html", data)
+		return
+	}
+
+	entries, total, err := app.auditLog.Search(filter, pageSize, data.Page.Offset())
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	data.Page.Total = total
+	data.AuditEntries = entries
+
+	app.render(w, http.StatusOK, "admin_audit.html", data)
+}
+
+// adminNext is where to go back to after an action: the listing page it was
+// taken from, or fallback. Only admin paths are followed, so it can't be used
+// as an open redirect.
+func adminNext(next, fallback string) string{
+	if strings.HasPrefix(next, "/admin/"){
+		return next
+	}
+	return fallback
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+	"github.com/AVSanjay-12/snippetbox/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+const maxSnippetFiles = 10
+
+// The most a TEXT column holds
+const maxFileSize = 65535
+
+// File names go in URLs, element IDs and archives as they are, so they are
+// kept to characters that are safe in all three.
+var fileNameRX = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+func validFileName(name string) bool{
+	return validator.Matches(name, fileNameRX) && name != "." && name != ".."
+}
+
+type snippetFileForm struct{
+	Name string		`form:"name"`
+	Language string	`form:"language"`
+	Content string	`form:"content"`
+}
+
+// snippetFilesForm is the title and files, shared by the create and edit
+// forms. Without JavaScript, files are added and removed by posting the form
+// back with add_file or remove_file set.
+type snippetFilesForm struct{
+	Title string				`form:"title"`
+	Files []snippetFileForm		`form:"files"`
+	AddFile bool				`form:"add_file"`
+	RemoveFile *int				`form:"remove_file"`
+}
+
+func newSnippetFilesForm(title string, files []models.SnippetFile) snippetFilesForm{
+	f := snippetFilesForm{Title: title}
+	for _, file := range files{
+		f.Files = append(f.Files, snippetFileForm{Name: file.Name, Language: file.Language, Content: file.Content})
+	}
+	if len(f.Files) == 0{
+		f.Files = []snippetFileForm{{}}
+	}
+	return f
+}
+
+// changeFiles adds or removes a file if that's what the form was posted for,
+// and reports whether it was.
+func (f *snippetFilesForm) changeFiles() bool{
+	switch{
+	case f.AddFile:
+		if len(f.Files) < maxSnippetFiles{
+			f.Files = append(f.Files, snippetFileForm{})
+		}
+	case f.RemoveFile != nil:
+		i := *f.RemoveFile
+		if i >= 0 && i < len(f.Files) && len(f.Files) > 1{
+			f.Files = append(f.Files[:i], f.Files[i+1:]...)
+		}
+	default:
+		return false
+	}
+
+	f.AddFile, f.RemoveFile = false, nil
+	return true
+}
+
+// normalize drops files left empty, names any without one and fills in
+// languages that were left to be detected.
+func (f *snippetFilesForm) normalize(){
+	var files []snippetFileForm
+	for _, file := range f.Files{
+		file.Name = strings.TrimSpace(file.Name)
+		if file.Name == "" && strings.TrimSpace(file.Content) == ""{
+			continue
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0{
+		files = []snippetFileForm{{}}
+	}
+
+	for i := range files{
+		if files[i].Name == ""{
+			files[i].Name = fmt.Sprintf("file%d.txt", i+1)
+		}
+		if files[i].Language == ""{
+			files[i].Language = languageFor(files[i].Name)
+		}
+	}
+	f.Files = files
+}
+
+func (f *snippetFilesForm) check(v *validator.Validator){
+	v.CheckField(validator.NotBlank(f.Title), "title", "This field cannot be empty")
+	v.CheckField(validator.MaxChars(f.Title, 100), "title", "This field cannot be more than 100 characters long")
+	v.CheckField(len(f.Files) <= maxSnippetFiles, "files", fmt.Sprintf("A snippet can't have more than %d files", maxSnippetFiles))
+
+	// Names are unique regardless of case, as they are in the database
+	seen := map[string]bool{}
+	for i, file := range f.Files{
+		key := fmt.Sprintf("files.%d.", i)
+		v.CheckField(validFileName(file.Name), key+"name", "Use only letters, numbers, dots, dashes and underscores")
+		v.CheckField(validator.MaxChars(file.Name, 100), key+"name", "This field cannot be more than 100 characters long")
+		v.CheckField(!seen[strings.ToLower(file.Name)], key+"name", "Another file has this name")
+		v.CheckField(validLanguage(file.Language), key+"language", "Pick a language from the list")
+		v.CheckField(validator.NotBlank(file.Content), key+"content", "This field cannot be empty")
+		v.CheckField(len(file.Content) <= maxFileSize, key+"content", fmt.Sprintf("This file is too big; the limit is %d KiB", maxFileSize/1024))
+		seen[strings.ToLower(file.Name)] = true
+	}
+}
+
+func (f *snippetFilesForm) snippetFiles() []models.SnippetFile{
+	files := make([]models.SnippetFile, len(f.Files))
+	for i, file := range f.Files{
+		files[i] = models.SnippetFile{Name: file.Name, Language: file.Language, Content: file.Content}
+	}
+	return files
+}
+
+// snippetRaw serves one file of a snippet as plain text.
+func (app *application) snippetRaw(w http.ResponseWriter, r *http.Request){
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1{
+		app.notFound(w)
+		return
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil{
+		if errors.Is(err, models.ErrNoRecord){
+			app.notFound(w)
+		} else{
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	for _, file := range snippet.Files{
+		if file.Name == params.ByName("name"){
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte(file.Content))
+			return
+		}
+	}
+	app.notFound(w)
+}
+
+// snippetZIP downloads all of a snippet's files as an archive.
+func (app *application) snippetZIP(w http.ResponseWriter, r *http.Request){
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1{
+		app.notFound(w)
+		return
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil{
+		if errors.Is(err, models.ErrNoRecord){
+			app.notFound(w)
+		} else{
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	// Built first so an error can still get a proper response
+	buf := new(bytes.Buffer)
+	err = writeSnippetZIP(buf, snippet)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="snippet-%d.zip"`, snippet.ID))
+	buf.WriteTo(w)
+}
+
+func writeSnippetZIP(w io.Writer, snippet *models.Snippet) error{
+	zw := zip.NewWriter(w)
+
+	for _, file := range snippet.Files{
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: file.Name, Method: zip.Deflate, Modified: snippet.Created})
+		if err != nil{
+			return err
+		}
+		_, err = f.Write([]byte(file.Content))
+		if err != nil{
+			return err
+		}
+	}
+
+	return zw.Close()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+	"github.com/go-playground/form"
+)
+
+func TestSnippetFilesForm(t *testing.T){
+	decoder := form.NewDecoder()
+	decoder.SetMaxArraySize(maxSnippetFiles)
+
+	values := url.Values{
+		"title": {"Config"},
+		"files[0].name": {" Dockerfile "},
+		"files[0].content": {"FROM scratch"},
+		"files[2].content": {"port = 80"},
+		"files[3].name": {"dockerfile"},
+		"files[3].language": {"yaml"},
+		"files[3].content": {"x: 1"},
+		"expires": {"7"},
+	}
+
+	var f snippetCreateForm
+	err := decoder.Decode(&f, values)
+	if err != nil{
+		t.Fatal(err)
+	}
+	assert.Equal(t, f.Title, "Config")
+	assert.Equal(t, len(f.Files), 4)
+	assert.Equal(t, f.changeFiles(), false)
+
+	f.normalize()
+	assert.Equal(t, len(f.Files), 3)
+	assert.Equal(t, f.Files[0].Name, "Dockerfile")
+	assert.Equal(t, f.Files[0].Language, "dockerfile")
+	assert.Equal(t, f.Files[1].Name, "file2.txt")
+	assert.Equal(t, f.Files[1].Language, "text")
+	assert.Equal(t, f.Files[2].Language, "yaml")
+
+	f.check(&f.Validator)
+	assert.Equal(t, len(f.FieldErrors), 1)
+	assert.Equal(t, f.FieldErrors["files.2.name"], "Another file has this name")
+
+	var removing snippetFilesForm
+	err = decoder.Decode(&removing, url.Values{"remove_file": {"0"}})
+	if err != nil{
+		t.Fatal(err)
+	}
+	assert.Equal(t, removing.RemoveFile != nil && *removing.RemoveFile == 0, true)
+
+	// Past the limit the form doesn't decode at all
+	values.Set("files[10].name", "a.txt")
+	err = decoder.Decode(&snippetCreateForm{}, values)
+	assert.Equal(t, err != nil, true)
+}
+
+func TestSnippetFilesFormChangeFiles(t *testing.T){
+	f := snippetFilesForm{Files: []snippetFileForm{{Name: "a"}, {Name: "b"}}}
+
+	f.AddFile = true
+	assert.Equal(t, f.changeFiles(), true)
+	assert.Equal(t, len(f.Files), 3)
+	assert.Equal(t, f.AddFile, false)
+
+	remove := 0
+	f.RemoveFile = &remove
+	assert.Equal(t, f.changeFiles(), true)
+	assert.Equal(t, len(f.Files), 2)
+	assert.Equal(t, f.Files[0].Name, "b")
+
+	// The last file stays
+	f.Files = f.Files[:1]
+	f.RemoveFile = &remove
+	assert.Equal(t, f.changeFiles(), true)
+	assert.Equal(t, len(f.Files), 1)
+}
+
+func TestValidFileName(t *testing.T){
+	for name, want := range map[string]bool{
+		"main.go": true,
+		".env": true,
+		"Dockerfile": true,
+		"..": false,
+		"a/b.txt": false,
+		"a b.txt": false,
+		"<x>": false,
+		"": false,
+	}{
+		assert.Equal(t, validFileName(name), want)
+	}
+}
+
+func TestLanguageFor(t *testing.T){
+	for name, want := range map[string]string{
+		"main.go": "go",
+		"Dockerfile": "dockerfile",
+		"app.Dockerfile": "dockerfile",
+		"docker-compose.YML": "yaml",
+		"Makefile": "makefile",
+		"notes": "text",
+		"data.xyz": "text",
+	}{
+		assert.Equal(t, languageFor(name), want)
+	}
+}
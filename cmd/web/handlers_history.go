@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/AVSanjay-12/snippetbox/internal/diff"
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Unchanged lines shown around each change
+const diffContext = 3
+
+// revisionDiff is what changed between two revisions of a snippet.
+type revisionDiff struct{
+	From *models.SnippetRevision
+	To *models.SnippetRevision
+	// Only the files that changed
+	Files []fileDiff
+}
+
+// fileDiff is the change to one file, matched between revisions by name.
+type fileDiff struct{
+	Name string
+	// added, removed or changed
+	Status string
+	OldLanguage string
+	NewLanguage string
+	Hunks []diff.Hunk
+}
+
+func (d *revisionDiff) TitleChanged() bool{
+	return d.From.Title != d.To.Title
+}
+
+// diffFiles compares two sets of files, in the order of the newer set with
+// removed files last.
+func diffFiles(from, to []models.SnippetFile) []fileDiff{
+	old := map[string]models.SnippetFile{}
+	for _, f := range from{
+		old[f.Name] = f
+	}
+
+	var files []fileDiff
+	for _, f := range to{
+		prev, ok := old[f.Name]
+		delete(old, f.Name)
+
+		switch{
+		case !ok:
+			files = append(files, fileDiff{Name: f.Name, Status: "added", NewLanguage: f.Language, Hunks: diff.Unified("", f.Content, diffContext)})
+		case prev != f:
+			files = append(files, fileDiff{Name: f.Name, Status: "changed", OldLanguage: prev.Language, NewLanguage: f.Language, Hunks: diff.Unified(prev.Content, f.Content, diffContext)})
+		}
+	}
+	for _, f := range from{
+		if _, ok := old[f.Name]; ok{
+			files = append(files, fileDiff{Name: f.Name, Status: "removed", OldLanguage: f.Language, Hunks: diff.Unified(f.Content, "", diffContext)})
+		}
+	}
+	return files
+}
+
+// snippetHistory lists a snippet's revisions and shows the diff between the
+// two picked with ?from= and ?to=, by default the latest change.
+func (app *application) snippetHistory(w http.ResponseWriter, r *http.Request){
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1{
+		app.notFound(w)
+		return
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil{
+		if errors.Is(err, models.ErrNoRecord){
+			app.notFound(w)
+		} else{
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	revisions, err := app.snippets.Revisions(id)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Revisions = revisions
+	data.IsOwner = snippet.UserID != 0 && snippet.UserID == app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	if len(revisions) > 0{
+		from, to := revisions[0].Number-1, revisions[0].Number
+		query := r.URL.Query()
+		if query.Has("from") || query.Has("to"){
+			from, err = strconv.Atoi(query.Get("from"))
+			if err != nil{
+				app.clientError(w, http.StatusBadRequest)
+				return
+			}
+			to, err = strconv.Atoi(query.Get("to"))
+			if err != nil{
+				app.clientError(w, http.StatusBadRequest)
+				return
+			}
+		}
+
+		if from > 0{
+			data.Diff, err = app.revisionDiff(id, from, to)
+			if err != nil{
+				if errors.Is(err, models.ErrNoRecord){
+					app.notFound(w)
+				} else{
+					app.serverError(w, err)
+				}
+				return
+			}
+		}
+	}
+
+	app.render(w, http.StatusOK, "history.html", data)
+}
+
+func (app *application) revisionDiff(id, from, to int) (*revisionDiff, error){
+	d := &revisionDiff{}
+
+	var err error
+	d.From, err = app.snippets.Revision(id, from)
+	if err != nil{
+		return nil, err
+	}
+	d.To, err = app.snippets.Revision(id, to)
+	if err != nil{
+		return nil, err
+	}
+
+	d.Files = diffFiles(d.From.Files, d.To.Files)
+	return d, nil
+}
+
+type snippetRestoreForm struct{
+	Revision int	`form:"revision"`
+}
+
+// snippetRestorePost puts an old revision back, as a new revision so
+// nothing in between is lost.
+func (app *application) snippetRestorePost(w http.ResponseWriter, r *http.Request){
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok{
+		return
+	}
+
+	var form snippetRestoreForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil || form.Revision < 1{
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	number := form.Revision
+
+	restored, err := app.snippets.Restore(snippet.ID, number, snippet.UserID)
+	if err != nil{
+		if errors.Is(err, models.ErrNoRecord){
+			app.notFound(w)
+		} else{
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	if restored != 0{
+		app.audit(r, models.AuditSnippetEdit, map[string]any{"snippet_id": snippet.ID, "revision": restored, "restored_from": number})
+		app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Revision #%d restored as #%d.", number, restored))
+	} else{
+		app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("The snippet already matches revision #%d.", number))
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/history/%d", snippet.ID), http.StatusSeeOther)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+)
+
+// userLoginOIDC sends the user to the identity provider. The state, nonce and
+// PKCE verifier stay behind in the session for the callback to check.
+func (app *application) userLoginOIDC(w http.ResponseWriter, r *http.Request){
+	if app.oidc == nil{
+		app.notFound(w)
+		return
+	}
+
+	login, err := newOIDCLogin()
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	url, err := app.oidc.authCodeURL(r.Context(), login)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "oidcState", login.State)
+	app.sessionManager.Put(r.Context(), "oidcNonce", login.Nonce)
+	app.sessionManager.Put(r.Context(), "oidcVerifier", login.Verifier)
+
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+func (app *application) userLoginOIDCCallback(w http.ResponseWriter, r *http.Request){
+	if app.oidc == nil{
+		app.notFound(w)
+		return
+	}
+
+	// Each login attempt can only come back once
+	login := oidcLogin{
+		State: app.sessionManager.PopString(r.Context(), "oidcState"),
+		Nonce: app.sessionManager.PopString(r.Context(), "oidcNonce"),
+		Verifier: app.sessionManager.PopString(r.Context(), "oidcVerifier"),
+	}
+
+	// A stale or forged callback, e.g. one replayed from the browser history
+	query := r.URL.Query()
+	if login.State == "" || subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(login.State)) != 1{
+		app.oidcFailed(w, r, errors.New("oidc: state mismatch"))
+		return
+	}
+
+	// The user cancelled, or the provider turned them away
+	if query.Get("error") != ""{
+		app.sessionManager.Put(r.Context(), "flash", "Single sign-on didn't complete. Please try again.")
+		http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		return
+	}
+
+	claims, err := app.oidc.exchange(r.Context(), login, query.Get("code"))
+	if err != nil{
+		if errors.Is(err, errOIDCNotAllowed){
+			app.sessionManager.Put(r.Context(), "flash", "That account can't be used to log in here.")
+			http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		} else{
+			// A used or expired code or a token that doesn't verify is down to
+			// the client or the provider, not us
+			app.oidcFailed(w, r, err)
+		}
+		return
+	}
+
+	id, err := app.oidcUser(r, claims)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	user, err := app.users.Get(id)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	if user.Disabled{
+		app.auditAs(r, id, models.AuditLoginFailed, map[string]any{"email": user.Email, "reason": "disabled"})
+		app.sessionManager.Put(r.Context(), "flash", "This account has been disabled.")
+		http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		return
+	}
+
+	// The account may have been linked by email, so the provider vouching
+	// for it doesn't replace a second factor turned on here
+	if user.TOTPEnabled{
+		err = app.beginTOTPLogin(r, id, false)
+		if err != nil{
+			app.serverError(w, err)
+			return
+		}
+
+		http.Redirect(w, r, "/user/login/totp", http.StatusSeeOther)
+		return
+	}
+
+	err = app.logIn(r, id, "oidc")
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/snippet/create", http.StatusSeeOther)
+}
+
+// oidcFailed logs why a single sign-on callback failed and sends the user
+// back to the login page.
+func (app *application) oidcFailed(w http.ResponseWriter, r *http.Request, err error){
+	app.errorLog.Printf("oidc: sign-in failed: %v", err)
+	app.sessionManager.Put(r.Context(), "flash", "Sign-in failed. Please try again.")
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
+
+// oidcUser returns the local user for an identity at the provider. The first
+// time someone signs in, they are matched to an account by email or get a new
+// one.
+func (app *application) oidcUser(r *http.Request, claims *oidcClaims) (int, error){
+	id, err := app.identities.UserID(app.oidc.issuer, claims.Subject)
+	if err == nil{
+		return id, nil
+	}
+	if !errors.Is(err, models.ErrNoRecord){
+		return 0, err
+	}
+
+	name := claims.Name
+	if name == ""{
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	id, err = app.externalUser(r.Context(), name, claims.Email)
+	if err != nil{
+		return 0, err
+	}
+
+	err = app.identities.Link(app.oidc.issuer, claims.Subject, id)
+	if err != nil{
+		return 0, err
+	}
+	return id, nil
+}
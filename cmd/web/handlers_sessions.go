@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+)
+
+// accountSessions lists everywhere the user is signed in.
+func (app *application) accountSessions(w http.ResponseWriter, r *http.Request){
+	userID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	sessions, err := app.sessions.ForUser(userID, app.sessionManager.GetString(r.Context(), "sessionID"))
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Sessions = sessions
+	app.render(w, http.StatusOK, "sessions.html", data)
+}
+
+type accountSessionRevokeForm struct{
+	ID string	`form:"id"`
+}
+
+// accountSessionRevokePost signs out one of the user's other sessions. The
+// current one is signed out by logging out.
+func (app *application) accountSessionRevokePost(w http.ResponseWriter, r *http.Request){
+	var form accountSessionRevokeForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil{
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	userID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	if form.ID == app.sessionManager.GetString(r.Context(), "sessionID"){
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = app.sessions.Delete(form.ID, userID)
+	if err != nil{
+		if errors.Is(err, models.ErrNoRecord){
+			app.notFound(w)
+		} else{
+			app.serverError(w, err)
+		}
+		return
+	}
+	app.audit(r, models.AuditSessionRevoke, map[string]any{"session_id": form.ID})
+
+	app.sessionManager.Put(r.Context(), "flash", "That session has been signed out.")
+	http.Redirect(w, r, "/account/sessions", http.StatusSeeOther)
+}
+
+func (app *application) accountSessionsRevokeOthersPost(w http.ResponseWriter, r *http.Request){
+	userID := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	err := app.revokeSessions(userID, app.sessionManager.GetString(r.Context(), "sessionID"))
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	app.audit(r, models.AuditSessionRevoke, map[string]any{"session_id": "others"})
+
+	app.sessionManager.Put(r.Context(), "flash", "You have been signed out everywhere else.")
+	http.Redirect(w, r, "/account/sessions", http.StatusSeeOther)
+}
@@ -2,12 +2,16 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/AVSanjay-12/snippetbox/internal/assert"
+	"github.com/alexedwards/scs/v2"
+	"github.com/alexedwards/scs/v2/memstore"
 )
 
 func TestPing(t *testing.T){
@@ -33,4 +37,64 @@ func TestPing(t *testing.T){
 
 	assert.Equal(t, string(body), "OK");
 
-}
\ No newline at end of file
+}
+
+func TestDestroySessions(t *testing.T){
+	sessionManager := scs.New()
+	sessionManager.Store = memstore.New()
+	app := &application{sessionManager: sessionManager}
+
+	// Four sessions, the first two of which are destroyed
+	var tokens []string
+	for i := 0; i < 4; i++{
+		ctx, err := sessionManager.Load(context.Background(), "")
+		if err != nil{
+			t.Fatal(err)
+		}
+		sessionManager.Put(ctx, "sessionID", fmt.Sprintf("session-%d", i))
+
+		token, _, err := sessionManager.Commit(ctx)
+		if err != nil{
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	err := app.destroySessions(tokens[:2])
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	for i, token := range tokens{
+		_, found, err := sessionManager.Store.Find(token)
+		if err != nil{
+			t.Fatal(err)
+		}
+		assert.Equal(t, found, i >= 2)
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T){
+	codes, err := newRecoveryCodes()
+	if err != nil{
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(codes), recoveryCodeCount)
+	assert.Equal(t, normalizeRecoveryCode(codes[0]), codes[0])
+
+	tests := []struct{
+		name string
+		code string
+		want string
+	}{
+		{name: "As shown", code: "k3v9q-x2m7p", want: "k3v9q-x2m7p"},
+		{name: "No dash", code: "k3v9qx2m7p", want: "k3v9q-x2m7p"},
+		{name: "Capitals and spaces", code: " K3V9Q X2M7P ", want: "k3v9q-x2m7p"},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			assert.Equal(t, normalizeRecoveryCode(tt.code), tt.want)
+		})
+	}
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+	"github.com/AVSanjay-12/snippetbox/internal/totp"
+	"github.com/AVSanjay-12/snippetbox/internal/validator"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	// Time allowed between the password and the code steps of a login
+	totpLoginTimeout = 5 * time.Minute
+	totpIssuer = "Snippetbox"
+	recoveryCodeCount = 10
+)
+
+// newRecoveryCodes returns codes formatted like "k3v9q-x2m7p".
+func newRecoveryCodes() ([]string, error){
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes{
+		b := make([]byte, 7)
+		_, err := rand.Read(b)
+		if err != nil{
+			return nil, err
+		}
+
+		s := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:10]
+		codes[i] = s[:5] + "-" + s[5:]
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode accepts codes typed without the dash, with spaces or
+// in capitals.
+func normalizeRecoveryCode(code string) string{
+	code = strings.ToLower(strings.Join(strings.Fields(code), ""))
+	code = strings.ReplaceAll(code, "-", "")
+	if len(code) != 10{
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
+
+func (app *application) accountTOTP(w http.ResponseWriter, r *http.Request){
+	user, err := app.users.Get(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.User = user
+	data.Form = accountTOTPDisableForm{}
+	app.render(w, http.StatusOK, "totp.html", data)
+}
+
+// accountTOTPSetupPost starts enrollment with a new secret, kept in the
+// session until the user proves their app has it.
+func (app *application) accountTOTPSetupPost(w http.ResponseWriter, r *http.Request){
+	user, err := app.users.Get(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	if user.TOTPEnabled{
+		app.refuseTOTPEnrollment(w, r)
+		return
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "totpPendingSecret", secret)
+	http.Redirect(w, r, "/account/2fa/enable", http.StatusSeeOther)
+}
+
+type accountTOTPEnableForm struct{
+	Code string			`form:"code"`
+	validator.Validator	`form:"-"`
+}
+
+func (app *application) accountTOTPEnable(w http.ResponseWriter, r *http.Request){
+	data, ok := app.totpEnrollmentData(w, r)
+	if !ok{
+		return
+	}
+
+	data.Form = accountTOTPEnableForm{}
+	app.render(w, http.StatusOK, "totp_enable.html", data)
+}
+
+// totpEnrollmentData fills in what the enrollment page shows. If there is no
+// enrollment in progress it redirects to start one.
+func (app *application) totpEnrollmentData(w http.ResponseWriter, r *http.Request) (*templateData, bool){
+	secret := app.sessionManager.GetString(r.Context(), "totpPendingSecret")
+	if secret == ""{
+		http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+		return nil, false
+	}
+
+	user, err := app.users.Get(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil{
+		app.serverError(w, err)
+		return nil, false
+	}
+	if user.TOTPEnabled{
+		app.refuseTOTPEnrollment(w, r)
+		return nil, false
+	}
+
+	data := app.newTemplateData(r)
+	data.User = user
+	data.TOTPSecret = secret
+	data.TOTPURI = totp.URI(totpIssuer, user.Email, secret)
+	return data, true
+}
+
+// refuseTOTPEnrollment turns away enrolling while two-factor authentication
+// is on. Enabling replaces the secret and recovery codes, so a stolen session
+// could otherwise take over the second factor without the password that
+// turning it off asks for.
+func (app *application) refuseTOTPEnrollment(w http.ResponseWriter, r *http.Request){
+	app.sessionManager.Remove(r.Context(), "totpPendingSecret")
+	app.sessionManager.Put(r.Context(), "flash", "Two-factor authentication is already on. Turn it off first to set it up again.")
+	http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+}
+
+func (app *application) accountTOTPQRCode(w http.ResponseWriter, r *http.Request){
+	secret := app.sessionManager.GetString(r.Context(), "totpPendingSecret")
+	if secret == ""{
+		app.notFound(w)
+		return
+	}
+
+	user, err := app.users.Get(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	png, err := qrcode.Encode(totp.URI(totpIssuer, user.Email, secret), qrcode.Medium, 256)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(png)
+}
+
+func (app *application) accountTOTPEnablePost(w http.ResponseWriter, r *http.Request){
+	var form accountTOTPEnableForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil{
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	data, ok := app.totpEnrollmentData(w, r)
+	if !ok{
+		return
+	}
+
+	_, valid := totp.Validate(data.TOTPSecret, form.Code, time.Now(), 0)
+	form.CheckField(valid, "code", "That code isn't right. Check the time on your device and try again.")
+
+	if !form.Valid(){
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "totp_enable.html", data)
+		return
+	}
+
+	codes, err := newRecoveryCodes()
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.totp.Enable(data.User.ID, data.TOTPSecret, codes)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	app.sessionManager.Remove(r.Context(), "totpPendingSecret")
+
+	// Shown once - only their hashes are kept
+	data = app.newTemplateData(r)
+	data.RecoveryCodes = codes
+	app.render(w, http.StatusOK, "totp_recovery.html", data)
+}
+
+type accountTOTPDisableForm struct{
+	Password string		`form:"password"`
+	validator.Validator	`form:"-"`
+}
+
+func (app *application) accountTOTPDisablePost(w http.ResponseWriter, r *http.Request){
+	var form accountTOTPDisableForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil{
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	user, err := app.users.Get(app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	_, err = app.authenticator.Authenticate(user.Email, form.Password)
+	if err != nil{
+		if errors.Is(err, models.ErrInvalidCredentials){
+			form.AddFieldErrors("password", "Password is incorrect")
+			data := app.newTemplateData(r)
+			data.User = user
+			data.Form = form
+			app.render(w, http.StatusUnprocessableEntity, "totp.html", data)
+		} else{
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	err = app.totp.Disable(user.ID)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Two-factor authentication is off.")
+	http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+}
+
+type userLoginTOTPForm struct{
+	Code string			`form:"code"`
+	validator.Validator	`form:"-"`
+}
+
+// beginTOTPLogin leaves a user who has passed the first step of a login
+// waiting for their code. remember is whether to remember them once it passes.
+func (app *application) beginTOTPLogin(r *http.Request, userID int, remember bool) error{
+	err := app.sessionManager.RenewToken(r.Context())
+	if err != nil{
+		return err
+	}
+
+	app.sessionManager.Put(r.Context(), "totpPendingUserID", userID)
+	app.sessionManager.Put(r.Context(), "totpPendingExpires", time.Now().Add(totpLoginTimeout).Unix())
+	app.sessionManager.Put(r.Context(), "totpPendingRemember", remember)
+	return nil
+}
+
+// pendingTOTPUser returns the user who passed the password step of a login
+// in this session, if that was recently enough.
+func (app *application) pendingTOTPUser(r *http.Request) (*models.User, error){
+	id := app.sessionManager.GetInt(r.Context(), "totpPendingUserID")
+	expires := app.sessionManager.GetInt64(r.Context(), "totpPendingExpires")
+	if id == 0 || time.Now().Unix() > expires{
+		return nil, models.ErrNoRecord
+	}
+
+	return app.users.Get(id)
+}
+
+func (app *application) userLoginTOTP(w http.ResponseWriter, r *http.Request){
+	_, err := app.pendingTOTPUser(r)
+	if err != nil{
+		if errors.Is(err, models.ErrNoRecord){
+			http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		} else{
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = userLoginTOTPForm{}
+	app.render(w, http.StatusOK, "login_totp.html", data)
+}
+
+func (app *application) userLoginTOTPPost(w http.ResponseWriter, r *http.Request){
+	var form userLoginTOTPForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil{
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	user, err := app.pendingTOTPUser(r)
+	if err != nil{
+		if errors.Is(err, models.ErrNoRecord){
+			app.sessionManager.Put(r.Context(), "flash", "Your login timed out. Please log in again.")
+			http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		} else{
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	ip := app.clientIP(r)
+
+	// Codes are guessable too, so they count towards the same lockout
+	locked, err := app.loginThrottle.check(user.Email, ip)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	if locked{
+		form.AddNonFieldErrors("Too many failed login attempts. Please try again later.")
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusTooManyRequests, "login_totp.html", data)
+		return
+	}
+
+	ok, err := app.checkSecondFactor(user.ID, form.Code)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	if !ok{
+		app.auditAs(r, user.ID, models.AuditLoginFailed, map[string]any{"email": user.Email, "reason": "invalid_code"})
+
+		err = app.loginThrottle.fail(user.Email, ip)
+		if err != nil{
+			app.serverError(w, err)
+			return
+		}
+
+		form.AddNonFieldErrors("Invalid code")
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "login_totp.html", data)
+		return
+	}
+
+	remember := app.sessionManager.GetBool(r.Context(), "totpPendingRemember")
+
+	err = app.logIn(r, user.ID, "totp")
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	if remember{
+		err = app.remember(w, r, user.ID)
+		if err != nil{
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	http.Redirect(w, r, "/snippet/create", http.StatusSeeOther)
+}
+
+// checkSecondFactor accepts a current authenticator code or an unused
+// recovery code.
+func (app *application) checkSecondFactor(userID int, code string) (bool, error){
+	secret, lastStep, err := app.totp.Get(userID)
+	if err != nil{
+		return false, err
+	}
+	if secret == ""{
+		return false, nil
+	}
+
+	if step, ok := totp.Validate(secret, code, time.Now(), lastStep); ok{
+		// Another request may have used the same code in the meantime
+		return app.totp.MarkUsed(userID, step)
+	}
+
+	return app.totp.UseRecoveryCode(userID, normalizeRecoveryCode(code))
+}
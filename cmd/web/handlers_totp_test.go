@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+	"github.com/AVSanjay-12/snippetbox/internal/totp"
+	"github.com/alexedwards/scs/v2"
+	"github.com/alexedwards/scs/v2/memstore"
+	"github.com/go-playground/form"
+)
+
+func TestTOTPEnrollWhileEnabled(t *testing.T){
+	secret, err := totp.NewSecret()
+	if err != nil{
+		t.Fatal(err)
+	}
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	tests := []struct{
+		name string
+		enabled bool
+		handler func(*application) http.HandlerFunc
+		body url.Values
+		wantLocation string
+		wantEnabled bool
+	}{
+		{
+			name: "Setup",
+			enabled: true,
+			handler: func(app *application) http.HandlerFunc{ return app.accountTOTPSetupPost },
+			wantLocation: "/account/2fa",
+		},
+		{
+			name: "Enable",
+			enabled: true,
+			handler: func(app *application) http.HandlerFunc{ return app.accountTOTPEnablePost },
+			body: url.Values{"code": {code}},
+			wantLocation: "/account/2fa",
+		},
+		{
+			name: "Enable when off",
+			handler: func(app *application) http.HandlerFunc{ return app.accountTOTPEnablePost },
+			body: url.Values{"code": {code}},
+			wantEnabled: true,
+		},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			db, stub := newStubDB(map[string][][]driver.Value{
+				"FROM users WHERE id = ?": {userRow(1, "Alice", "alice@example.com", tt.enabled)},
+			})
+			defer db.Close()
+
+			sessionManager := scs.New()
+			sessionManager.Store = memstore.New()
+			app := &application{
+				errorLog: log.New(io.Discard, "", 0),
+				cfg: defaultConfig(),
+				users: &models.UserModel{DB: db},
+				totp: &models.TOTPModel{DB: db},
+				sessionManager: sessionManager,
+				formDecoder: form.NewDecoder(),
+			}
+
+			// Enrollment was started before, or in another tab. With no
+			// templates, a successful enable fails at showing the codes, after
+			// the secret is stored.
+			token := newSession(t, sessionManager, map[string]any{"authenticatedUserID": 1, "totpPendingSecret": secret})
+
+			r, err := http.NewRequest(http.MethodPost, "/account/2fa", strings.NewReader(tt.body.Encode()))
+			if err != nil{
+				t.Fatal(err)
+			}
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r.AddCookie(&http.Cookie{Name: sessionManager.Cookie.Name, Value: token})
+
+			rr := httptest.NewRecorder()
+			sessionManager.LoadAndSave(tt.handler(app)).ServeHTTP(rr, r)
+
+			assert.Equal(t, rr.Header().Get("Location"), tt.wantLocation)
+			assert.Equal(t, stub.executed("totp_secret"), tt.wantEnabled)
+
+			ctx, err := sessionManager.Load(context.Background(), token)
+			if err != nil{
+				t.Fatal(err)
+			}
+			assert.Equal(t, sessionManager.GetString(ctx, "totpPendingSecret"), "")
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// How long each readiness check is allowed to take before it counts as failed.
+const readinessTimeout = 2 * time.Second
+
+type healthCheck struct{
+	Status string		`json:"status"`
+	LatencyMS float64	`json:"latency_ms"`
+	Error string		`json:"error,omitempty"`
+}
+
+type healthReport struct{
+	Status string					`json:"status"`
+	Checks map[string]healthCheck	`json:"checks,omitempty"`
+}
+
+// Liveness - the process is up and able to serve requests
+func (app *application) healthz(w http.ResponseWriter, r *http.Request){
+	ping(w, r)
+}
+
+// Readiness - dependencies are reachable and the server is not shutting down
+func (app *application) readyz(w http.ResponseWriter, r *http.Request){
+	if app.shuttingDown.Load(){
+		app.writeHealth(w, http.StatusServiceUnavailable, healthReport{Status: "shutting down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	report := healthReport{
+		Status: "ok",
+		Checks: map[string]healthCheck{
+			"database": runCheck(ctx, app.checkDatabase),
+			"templates": runCheck(ctx, app.checkTemplates),
+			"sessions": runCheck(ctx, app.checkSessionStore),
+		},
+	}
+
+	status := http.StatusOK
+	for _, check := range report.Checks{
+		if check.Status != "ok"{
+			report.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	app.writeHealth(w, status, report)
+}
+
+func runCheck(ctx context.Context, check func(context.Context) error) healthCheck{
+	start := time.Now()
+	err := check(ctx)
+
+	result := healthCheck{
+		Status: "ok",
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil{
+		result.Status = "fail"
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (app *application) checkDatabase(ctx context.Context) error{
+	if app.db == nil{
+		return errors.New("database not configured")
+	}
+	return app.db.PingContext(ctx)
+}
+
+func (app *application) checkTemplates(ctx context.Context) error{
+	cache := app.templateCache.Load()
+	if cache == nil || len(*cache) == 0{
+		return errors.New("template cache is empty")
+	}
+	return nil
+}
+
+// The session store has no ping of its own, so look up a token that can never
+// exist - a round trip without an error means the store is usable.
+func (app *application) checkSessionStore(ctx context.Context) error{
+	if app.sessionManager == nil || app.sessionManager.Store == nil{
+		return errors.New("session store not configured")
+	}
+
+	errCh := make(chan error, 1)
+	go func(){
+		_, _, err := app.sessionManager.Store.Find("readyz-probe")
+		errCh <- err
+	}()
+
+	select{
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (app *application) writeHealth(w http.ResponseWriter, status int, report healthReport){
+	js, err := json.Marshal(report)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(js)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"text/template"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+	"github.com/alexedwards/scs/v2"
+	"github.com/alexedwards/scs/v2/memstore"
+)
+
+// pingDriver is a database driver whose connections do nothing but answer
+// pings, standing in for MySQL.
+type pingDriver struct{}
+
+func (pingDriver) Open(string) (driver.Conn, error){ return pingConn{}, nil }
+
+type pingConn struct{}
+
+func (pingConn) Prepare(string) (driver.Stmt, error){ return nil, errors.New("not supported") }
+func (pingConn) Close() error{ return nil }
+func (pingConn) Begin() (driver.Tx, error){ return nil, errors.New("not supported") }
+
+func init(){
+	sql.Register("ping", pingDriver{})
+}
+
+func TestReadyz(t *testing.T){
+	tests := []struct{
+		name string
+		shuttingDown bool
+		// Database, templates and session store all available
+		healthy bool
+		wantCode int
+		wantStatus string
+	}{
+		{
+			name: "Ready",
+			healthy: true,
+			wantCode: http.StatusOK,
+			wantStatus: "ok",
+		},
+		{
+			name: "Shutting down",
+			shuttingDown: true,
+			wantCode: http.StatusServiceUnavailable,
+			wantStatus: "shutting down",
+		},
+		{
+			name: "Dependencies missing",
+			shuttingDown: false,
+			wantCode: http.StatusServiceUnavailable,
+			wantStatus: "unavailable",
+		},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			app := &application{
+				errorLog: log.New(io.Discard, "", 0),
+				infoLog: log.New(io.Discard, "", 0),
+			}
+			app.shuttingDown.Store(tt.shuttingDown)
+			if tt.healthy{
+				db, err := sql.Open("ping", "")
+				if err != nil{
+					t.Fatal(err)
+				}
+				defer db.Close()
+				app.db = db
+
+				cache := map[string]*template.Template{"home.html": template.New("home.html")}
+				app.templateCache.Store(&cache)
+
+				app.sessionManager = scs.New()
+				app.sessionManager.Store = memstore.New()
+			}
+
+			rr := httptest.NewRecorder()
+			r, err := http.NewRequest(http.MethodGet, "/readyz", nil)
+			if err != nil{
+				t.Fatal(err)
+			}
+
+			app.readyz(rr, r)
+
+			rs := rr.Result()
+			defer rs.Body.Close()
+
+			assert.Equal(t, rs.StatusCode, tt.wantCode)
+			assert.Equal(t, rs.Header.Get("Content-Type"), "application/json")
+
+			var report healthReport
+			err = json.NewDecoder(rs.Body).Decode(&report)
+			if err != nil{
+				t.Fatal(err)
+			}
+			assert.Equal(t, report.Status, tt.wantStatus)
+			if tt.healthy{
+				for name, check := range report.Checks{
+					assert.Equal(t, name+": "+check.Status, name+": ok")
+				}
+			}
+		})
+	}
+}
@@ -4,10 +4,15 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/netip"
 	"runtime/debug"
+	"strings"
 	"time"
 
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+	"github.com/AVSanjay-12/snippetbox/internal/validator"
 	"github.com/go-playground/form"
 	"github.com/justinas/nosurf"
 )
@@ -35,20 +40,40 @@ func (app *application) newTemplateData(r *http.Request) *templateData{
 		CurrentYear: time.Now().Year(),
 		Flash: app.sessionManager.PopString(r.Context(), "flash"),
 		IsAuthenticated: app.isAuthenticated(r),
+		IsVerified: app.isVerified(r),
+		IsAdmin: app.isAdmin(r),
 		CSRFToken: nosurf.Token(r),
+		OIDCName: app.oidcName(),
+		RememberMe: app.cfg.RememberMeLifetime > 0,
 	}
 }
 
+// oidcName is what the single sign-on button calls the provider, or empty
+// when single sign-on is off.
+func (app *application) oidcName() string{
+	if app.oidc == nil{
+		return ""
+	}
+	return app.cfg.OIDCDisplayName
+}
+
 func (app *application) render(w http.ResponseWriter, status int, page string, data *templateData){
-	ts, ok := app.templateCache[page]
+	cache, err := app.templates()
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	ts, ok := cache[page]
 	if !ok{
 		err := fmt.Errorf("the template %s does not exist", page)
 		app.serverError(w, err)
+		return
 	}
 
 	buf := new(bytes.Buffer)
 
-	err := ts.ExecuteTemplate(buf, "base", data)
+	err = ts.ExecuteTemplate(buf, "base", data)
 	if err != nil{
 		app.serverError(w, err)
 		return
@@ -59,6 +84,9 @@ func (app *application) render(w http.ResponseWriter, status int, page string, d
 	buf.WriteTo(w)
 }
 
+// Uploaded files past this are spooled to temporary files
+const multipartMaxMemory = 1 << 20
+
 func (app *application) decodePostForm(r *http.Request, dst any) error{
 
 	err := r.ParseForm()
@@ -66,6 +94,13 @@ func (app *application) decodePostForm(r *http.Request, dst any) error{
 		return err
 	}
 
+	// Multipart forms are parsed separately, leaving any uploaded files in
+	// r.MultipartForm
+	err = r.ParseMultipartForm(multipartMaxMemory)
+	if err != nil && !errors.Is(err, http.ErrNotMultipart){
+		return err
+	}
+
 	err = app.formDecoder.Decode(dst, r.PostForm)
 	if err != nil{
 		var invalidDecoderError *form.InvalidDecoderError
@@ -86,6 +121,176 @@ func (app *application) isAuthenticated(r *http.Request) bool{
 	return isAuthenticated
 }
 
+func (app *application) hasRole(r *http.Request, role string) bool{
+	userRole, ok := r.Context().Value(userRoleContextKey).(string)
+	if !ok{
+		return false
+	}
+
+	return userRole == role
+}
+
+func (app *application) isAdmin(r *http.Request) bool{
+	return app.hasRole(r, models.RoleAdmin)
+}
+
+func (app *application) requestIDFor(r *http.Request) string{
+	id, _ := r.Context().Value(requestIDContextKey).(string)
+	return id
+}
+
+func (app *application) isVerified(r *http.Request) bool{
+	isVerified, ok := r.Context().Value(isVerifiedContextKey).(bool)
+	if !ok{
+		return false
+	}
+
+	return isVerified
+}
+
+// checkPassword applies the password policy to a new password for the user
+// with this name and email, recording any problem against the form field key.
+func (app *application) checkPassword(v *validator.Validator, key, password, name, email string) error{
+	problem, err := app.passwordPolicy.Check(password, name, email)
+	if err != nil{
+		return err
+	}
+	v.CheckField(problem == "", key, problem)
+	return nil
+}
+
+// logIn finishes a login with a fresh session token, then adds the ID of the
+// user to the session, so that they are now 'logged in'. method says how they
+// proved who they are, for the audit log.
+func (app *application) logIn(r *http.Request, userID int, method string) error{
+	err := app.sessionManager.RenewToken(r.Context())
+	if err != nil{
+		return err
+	}
+
+	sessionID, err := app.sessions.Insert(userID, app.sessionManager.Token(r.Context()), app.clientIP(r), r.UserAgent())
+	if err != nil{
+		return err
+	}
+
+	app.sessionManager.Remove(r.Context(), "totpPendingUserID")
+	app.sessionManager.Remove(r.Context(), "totpPendingExpires")
+	app.sessionManager.Remove(r.Context(), "totpPendingRemember")
+	app.sessionManager.Put(r.Context(), "authenticatedUserID", userID)
+	app.sessionManager.Put(r.Context(), "sessionID", sessionID)
+	app.auditAs(r, userID, models.AuditLogin, map[string]any{"method": method})
+	return nil
+}
+
+// revokeSessions signs a user out everywhere except the session keep, which
+// may be empty.
+func (app *application) revokeSessions(userID int, keep string) error{
+	tokens, err := app.sessions.Tokens(userID, keep)
+	if err != nil{
+		return err
+	}
+	err = app.sessions.DeleteAllForUser(userID, keep)
+	if err != nil{
+		return err
+	}
+	return app.destroySessions(tokens)
+}
+
+// destroySessions deletes sessions from the store by token. Deleting their
+// records is enough to sign them out; this clears out what they held.
+func (app *application) destroySessions(tokens []string) error{
+	for _, token := range tokens{
+		err := app.sessionManager.Store.Delete(token)
+		if err != nil{
+			return err
+		}
+	}
+	return nil
+}
+
+// background runs fn in a goroutine that shutdown waits for, recovering any
+// panic so it can't take the server down.
+func (app *application) background(fn func()){
+	app.wg.Add(1)
+
+	go func(){
+		defer app.wg.Done()
+
+		defer func(){
+			if err := recover(); err != nil{
+				app.errorLog.Print(fmt.Errorf("%s", err))
+			}
+		}()
+
+		fn()
+	}()
+}
+
+// clientIP returns the address the request came from, without the port.
+// X-Forwarded-For is only believed when the connection is from a trusted
+// proxy, and then read from the right, skipping further trusted proxies - the
+// first untrusted hop is the client. Anything to its left could be forged.
+func (app *application) clientIP(r *http.Request) string{
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil{
+		ip = r.RemoteAddr
+	}
+
+	if !app.isTrustedProxy(ip){
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i--{
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil{
+			break
+		}
+		ip = hop
+		if !app.isTrustedProxy(hop){
+			break
+		}
+	}
+	return ip
+}
+
+func (app *application) isTrustedProxy(ip string) bool{
+	addr, err := netip.ParseAddr(ip)
+	if err != nil{
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range app.trustedProxies{
+		if prefix.Contains(addr){
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrustedProxies accepts CIDR ranges and bare addresses.
+func parseTrustedProxies(proxies []string) ([]netip.Prefix, error){
+	prefixes := []netip.Prefix{}
+	for _, p := range proxies{
+		if !strings.Contains(p, "/"){
+			addr, err := netip.ParseAddr(p)
+			if err != nil{
+				return nil, fmt.Errorf("%q is not an IP address or CIDR range", p)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil{
+			return nil, fmt.Errorf("%q is not an IP address or CIDR range", p)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
 func ping(w http.ResponseWriter, r *http.Request){
 	w.Write([]byte("OK"))
 }
\ No newline at end of file
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+type language struct{
+	// Stored with the file and used as its highlighting class
+	ID string
+	Name string
+}
+
+// languages a file can be marked as. Files from before languages were kept
+// have none, and are shown as plain text.
+var languages = []language{
+	{"text", "Plain text"},
+	{"bash", "Shell"},
+	{"c", "C"},
+	{"cpp", "C++"},
+	{"css", "CSS"},
+	{"dockerfile", "Dockerfile"},
+	{"go", "Go"},
+	{"html", "HTML"},
+	{"ini", "INI"},
+	{"java", "Java"},
+	{"javascript", "JavaScript"},
+	{"json", "JSON"},
+	{"makefile", "Makefile"},
+	{"markdown", "Markdown"},
+	{"nginx", "Nginx"},
+	{"python", "Python"},
+	{"ruby", "Ruby"},
+	{"rust", "Rust"},
+	{"sql", "SQL"},
+	{"toml", "TOML"},
+	{"typescript", "TypeScript"},
+	{"xml", "XML"},
+	{"yaml", "YAML"},
+}
+
+func validLanguage(id string) bool{
+	for _, l := range languages{
+		if l.ID == id{
+			return true
+		}
+	}
+	return false
+}
+
+// languageName is how a language is shown, e.g. "Go" for "go".
+func languageName(id string) string{
+	for _, l := range languages{
+		if l.ID == id{
+			return l.Name
+		}
+	}
+	return "Plain text"
+}
+
+var languageExtensions = map[string]string{
+	".sh": "bash",
+	".bash": "bash",
+	".c": "c",
+	".h": "c",
+	".cc": "cpp",
+	".cpp": "cpp",
+	".hpp": "cpp",
+	".css": "css",
+	".go": "go",
+	".html": "html",
+	".htm": "html",
+	".ini": "ini",
+	".cfg": "ini",
+	".conf": "ini",
+	".java": "java",
+	".js": "javascript",
+	".mjs": "javascript",
+	".json": "json",
+	".md": "markdown",
+	".py": "python",
+	".rb": "ruby",
+	".rs": "rust",
+	".sql": "sql",
+	".toml": "toml",
+	".ts": "typescript",
+	".xml": "xml",
+	".yaml": "yaml",
+	".yml": "yaml",
+}
+
+// languageFor guesses a file's language from its name, e.g. "go" for
+// main.go. Anything it doesn't know is plain text.
+func languageFor(name string) string{
+	name = strings.ToLower(path.Base(name))
+
+	switch{
+	case name == "dockerfile" || strings.HasPrefix(name, "dockerfile.") || strings.HasSuffix(name, ".dockerfile"):
+		return "dockerfile"
+	case name == "makefile" || name == "gnumakefile":
+		return "makefile"
+	case name == "nginx.conf":
+		return "nginx"
+	}
+
+	if id, ok := languageExtensions[path.Ext(name)]; ok{
+		return id
+	}
+	return "text"
+}
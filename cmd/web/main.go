@@ -1,16 +1,24 @@
 package main
 
 import (
-	"crypto/tls"
+	"crypto/rand"
 	"database/sql"
+	"errors"
 	"flag"
+	"fmt"
+	"io/fs"
 	"log"
-	"net/http"
+	"net/netip"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"text/template"
-	"time"
 
+	"github.com/AVSanjay-12/snippetbox/internal/mailer"
 	"github.com/AVSanjay-12/snippetbox/internal/models"
+	"github.com/AVSanjay-12/snippetbox/internal/validator"
+	"github.com/AVSanjay-12/snippetbox/ui"
 	"github.com/alexedwards/scs/mysqlstore"
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-playground/form"
@@ -22,31 +30,80 @@ type application struct{
 	infoLog *log.Logger
 	snippets *models.SnippetModel
 	users *models.UserModel
-	templateCache map[string]*template.Template
+	authenticator models.Authenticator
+	passwordResets *models.PasswordResetModel
+	totp *models.TOTPModel
+	identities *models.IdentityModel
+	auditLog *models.AuditModel
+	sessions *models.SessionModel
+	rememberTokens *models.RememberTokenModel
+	passwordPolicy *validator.PasswordPolicy
+	stats *models.StatsModel
+	oidc *oidcClient
+	templateCache atomic.Pointer[map[string]*template.Template]
+	certs *certReloader
+	ui fs.FS
+	static *staticFiles
+	loginThrottle *loginThrottle
+	rateLimiters *rateLimiters
+	trustedProxies []netip.Prefix
+	secretKey []byte
+	mailer *mailer.Mailer
+	wg sync.WaitGroup
 	formDecoder *form.Decoder
 	sessionManager *scs.SessionManager
+	cfg *config
+	db *sql.DB
+	shuttingDown atomic.Bool
 }
 
 func main() {
-	// For reading the cmd line 
-	addr := flag.String("addr", ":4000", "HTTP network address")
-	dsn := flag.String("dsn", "web:pass@/snippetbox?parseTime=true", "MySQL data source name")
-	
-	flag.Parse()
+	// Subcommands, e.g. "snippetbox config show"
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-"){
+		err := runCommand(os.Args[1], os.Args[2:])
+		if err != nil{
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Defaults, config file, environment and flags
+	cfg, err := loadConfig(os.Args[1:], os.LookupEnv)
+	if err != nil{
+		if errors.Is(err, flag.ErrHelp){
+			return
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
 	// Custom loggers for info and error
 	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
 	errorLog := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
 
-	db, err := openDB(*dsn)
+	db, err := openDB(cfg.DSN)
 	if err != nil{
 		errorLog.Fatal(err)
 	}
 
 	defer db.Close()
 
+	// UI assets are embedded in the binary unless -ui-dir points at a
+	// checkout to edit them live
+	var uiFiles fs.FS = ui.Files
+	if cfg.UIDir != ""{
+		uiFiles = os.DirFS(cfg.UIDir)
+	}
+
+	staticFS, err := fs.Sub(uiFiles, "static")
+	if err != nil{
+		errorLog.Fatal(err)
+	}
+	static := newStaticFiles(staticFS, cfg.UIDir == "")
+
 	// Initialize a new template cache
-	templateCache, err := newTemplateCache()
+	templateCache, err := newTemplateCache(uiFiles, static)
 	if err != nil{
 		errorLog.Fatal(err)
 		return 
@@ -54,40 +111,91 @@ func main() {
 
 	// Initialize a decoder instance
 	formDecoder := form.NewDecoder()
+	// The only lists posted are a snippet's files
+	formDecoder.SetMaxArraySize(maxSnippetFiles)
 
 	sessionManager := scs.New()
 	sessionManager.Store = mysqlstore.New(db)
-	sessionManager.Lifetime = 12 * time.Hour
+	sessionManager.Lifetime = cfg.SessionLifetime
 	sessionManager.Cookie.Secure = true
 
+	secretKey := []byte(cfg.SecretKey)
+	if len(secretKey) == 0{
+		secretKey = make([]byte, 32)
+		_, err = rand.Read(secretKey)
+		if err != nil{
+			errorLog.Fatal(err)
+		}
+		infoLog.Print("No secret_key configured, using a random one - emailed links will stop working on restart")
+	}
+
+	var mailSender mailer.Sender = &mailer.FileSender{Dir: cfg.MailDir, Log: infoLog, From: cfg.MailSender, Redact: !cfg.Dev}
+	if cfg.Mailer == "smtp"{
+		mailSender = &mailer.SMTPSender{
+			Host: cfg.SMTPHost,
+			Port: cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From: cfg.MailSender,
+		}
+	}
+
+	// Validated along with the rest of the config
+	trustedProxies, _ := parseTrustedProxies(cfg.TrustedProxies)
+
+	passwordPolicy := &validator.PasswordPolicy{MinLength: cfg.PasswordMinLength, MaxLength: cfg.PasswordMaxLength}
+	if cfg.PasswordBreachedCheck{
+		passwordPolicy.Breached = &validator.BreachedList{Dir: cfg.PasswordBreachedDir}
+	}
+
+	// Failed login tracking, shared through MySQL or kept in this process
+	var loginAttempts models.LoginAttemptStore = models.NewMemoryLoginAttempts()
+	if cfg.LoginThrottleStore == "database"{
+		loginAttempts = &models.LoginAttemptModel{DB: db}
+	}
+
 	// New instance of application struct - contains dependencies
 	app := &application{
 		errorLog: errorLog,
 		infoLog: infoLog,
 		snippets: &models.SnippetModel{DB: db},
-		users: &models.UserModel{DB: db},
-		templateCache: templateCache,
+		users: &models.UserModel{DB: db, Passwords: cfg.passwordPolicy()},
+		passwordResets: &models.PasswordResetModel{DB: db},
+		totp: &models.TOTPModel{DB: db},
+		identities: &models.IdentityModel{DB: db},
+		auditLog: &models.AuditModel{DB: db},
+		sessions: &models.SessionModel{DB: db, Lifetime: cfg.SessionLifetime},
+		rememberTokens: &models.RememberTokenModel{DB: db},
+		passwordPolicy: passwordPolicy,
+		stats: &models.StatsModel{DB: db},
+		oidc: newOIDCClient(cfg),
 		formDecoder: formDecoder,
 		sessionManager: sessionManager,
+		loginThrottle: &loginThrottle{
+			store: loginAttempts,
+			accountThreshold: cfg.LoginMaxFailures,
+			ipThreshold: cfg.LoginMaxFailuresIP,
+			lockoutBase: cfg.LoginLockout,
+			lockoutMax: cfg.LoginLockoutMax,
+			window: cfg.LoginFailureWindow,
+		},
+		rateLimiters: newRateLimiters(cfg),
+		trustedProxies: trustedProxies,
+		secretKey: secretKey,
+		mailer: mailer.New(mailSender),
+		ui: uiFiles,
+		static: static,
+		cfg: cfg,
+		db: db,
 	}
+	app.authenticator = app.newAuthenticator()
+	app.pruneAuditLog()
+	app.templateCache.Store(&templateCache)
 
-	tlsConfig := &tls.Config{
-		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
-	}
-
-	srv := &http.Server{
-		Addr: *addr,
-		ErrorLog: errorLog,
-		Handler: app.routes(),
-		TLSConfig: tlsConfig,
-		IdleTimeout: time.Minute,
-		ReadTimeout: 5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+	err = app.serve()
+	if err != nil{
+		errorLog.Fatal(err)
 	}
-
-	infoLog.Printf("Starting server on %s", *addr)
-	err = srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
-	errorLog.Fatal(err)
 }
 
 func openDB(dsn string) (*sql.DB, error){
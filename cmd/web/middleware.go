@@ -2,9 +2,16 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"regexp"
 
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+	"github.com/justinas/alice"
 	"github.com/justinas/nosurf"
 )
 
@@ -19,9 +26,31 @@ func secureHeaders(next http.Handler) http.Handler{
 	})
 }
 
+// Request IDs passed on by a trusted proxy are kept if they look sane
+var requestIDRX = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// requestID tags each request with an ID, sent back in X-Request-ID, so log
+// lines and audit entries can be tied to it.
+func (app *application) requestID(next http.Handler) http.Handler{
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+		id := r.Header.Get("X-Request-ID")
+
+		peer, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil || !app.isTrustedProxy(peer) || !requestIDRX.MatchString(id){
+			b := make([]byte, 16)
+			rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		ctx := context.WithValue(r.Context(), requestIDContextKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func (app *application) logRequest(next http.Handler) http.Handler{
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
-		app.infoLog.Printf("%s - %s %s %s", r.RemoteAddr, r.Proto, r.Method, r.URL.RequestURI())
+		app.infoLog.Printf("%s - %s %s %s %s", r.RemoteAddr, r.Proto, r.Method, r.URL.RequestURI(), app.requestIDFor(r))
 
 		next.ServeHTTP(w, r)
 	})
@@ -56,6 +85,51 @@ func (app *application) requireAuthentication(next http.Handler) http.Handler{
 	})
 }
 
+// requireVerifiedEmail must come after requireAuthentication.
+func (app *application) requireVerifiedEmail(next http.Handler) http.Handler{
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+		if !app.isVerified(r){
+			app.sessionManager.Put(r.Context(), "flash", "Please confirm your email address first.")
+			http.Redirect(w, r, "/user/verify/pending", http.StatusSeeOther)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireRole returns middleware that only lets users with the given role
+// through. It must come after requireAuthentication.
+func (app *application) requireRole(role string) alice.Constructor{
+	return func(next http.Handler) http.Handler{
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+			if !app.hasRole(r, role){
+				app.clientError(w, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitBody returns middleware that caps request bodies at n bytes. It must
+// come before noSurf, which parses the form to find the CSRF token.
+func (app *application) limitBody(n int64) alice.Constructor{
+	return func(next http.Handler) http.Handler{
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+			// Turn away bodies that say they're too big before reading them
+			if r.ContentLength > n{
+				app.clientError(w, http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func noSurf(next http.Handler) http.Handler{
 	csrfHandler := nosurf.New(next)
 	csrfHandler.SetBaseCookie(http.Cookie{
@@ -71,21 +145,65 @@ func (app *application) authenticate(next http.Handler) http.Handler{
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request)  {
 		id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
 		if id == 0{
-			next.ServeHTTP(w, r)
-			return
+			var err error
+			id, err = app.resumeLogin(w, r)
+			if err != nil{
+				app.serverError(w, err)
+				return
+			}
+			if id == 0{
+				next.ServeHTTP(w, r)
+				return
+			}
 		}
 
-		exists, err := app.users.Exists(id)
+		user, err := app.users.Get(id)
 		if err != nil{
-			app.serverError(w, err)
+			if errors.Is(err, models.ErrNoRecord){
+				next.ServeHTTP(w, r)
+			} else{
+				app.serverError(w, err)
+			}
 			return
 		}
 
-		if exists{
-			ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
-			r = r.WithContext(ctx)
+		// Sessions are destroyed when an account is disabled, but one could
+		// be in use at the time
+		if user.Disabled{
+			app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+			next.ServeHTTP(w, r)
+			return
 		}
 
+		// A session with no record was revoked, unless it began before
+		// sessions were tracked, in which case it gets one now
+		sessionID := app.sessionManager.GetString(r.Context(), "sessionID")
+		if sessionID == ""{
+			sessionID, err = app.sessions.Insert(id, app.sessionManager.Token(r.Context()), app.clientIP(r), r.UserAgent())
+			if err != nil{
+				app.serverError(w, err)
+				return
+			}
+			app.sessionManager.Put(r.Context(), "sessionID", sessionID)
+		} else{
+			err = app.sessions.Touch(sessionID, id, app.clientIP(r))
+			if err != nil{
+				if errors.Is(err, models.ErrNoRecord){
+					app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+					app.sessionManager.Remove(r.Context(), "sessionID")
+					next.ServeHTTP(w, r)
+				} else{
+					app.serverError(w, err)
+				}
+				return
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+		ctx = context.WithValue(ctx, isVerifiedContextKey, user.EmailVerified)
+		ctx = context.WithValue(ctx, userRoleContextKey, user.Role)
+		r = r.WithContext(ctx)
+
 		next.ServeHTTP(w, r)
 	})
 }
\ No newline at end of file
@@ -2,12 +2,17 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/AVSanjay-12/snippetbox/internal/assert"
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+	"github.com/go-playground/form"
 )
 
 func TestMiddleware(t *testing.T) {
@@ -63,4 +68,125 @@ func TestMiddleware(t *testing.T) {
 	bytes.TrimSpace(body)
 	assert.Equal(t, string(body), "OK")
 
-}
\ No newline at end of file
+}
+func TestRequireRole(t *testing.T){
+	app := &application{}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+		w.Write([]byte("OK"))
+	})
+
+	tests := []struct{
+		name string
+		role string
+		wantCode int
+	}{
+		{name: "Admin", role: models.RoleAdmin, wantCode: http.StatusOK},
+		{name: "Member", role: models.RoleMember, wantCode: http.StatusForbidden},
+		{name: "No role", wantCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			r, err := http.NewRequest(http.MethodGet, "/admin/users", nil)
+			if err != nil{
+				t.Fatal(err)
+			}
+			if tt.role != ""{
+				r = r.WithContext(context.WithValue(r.Context(), userRoleContextKey, tt.role))
+			}
+
+			rr := httptest.NewRecorder()
+			app.requireRole(models.RoleAdmin)(next).ServeHTTP(rr, r)
+
+			assert.Equal(t, rr.Code, tt.wantCode)
+		})
+	}
+}
+
+func TestRequestID(t *testing.T){
+	proxies, err := parseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil{
+		t.Fatal(err)
+	}
+	app := &application{trustedProxies: proxies}
+
+	var seen string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+		seen = app.requestIDFor(r)
+	})
+
+	tests := []struct{
+		name string
+		remoteAddr string
+		header string
+		wantKept bool
+	}{
+		{name: "Generated", remoteAddr: "203.0.113.5:1234"},
+		{name: "Untrusted peer", remoteAddr: "203.0.113.5:1234", header: "abc-123"},
+		{name: "Trusted proxy", remoteAddr: "10.1.2.3:1234", header: "abc-123", wantKept: true},
+		{name: "Trusted proxy, bad ID", remoteAddr: "10.1.2.3:1234", header: "<script>"},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			r, err := http.NewRequest(http.MethodGet, "/", nil)
+			if err != nil{
+				t.Fatal(err)
+			}
+			r.RemoteAddr = tt.remoteAddr
+			if tt.header != ""{
+				r.Header.Set("X-Request-ID", tt.header)
+			}
+
+			rr := httptest.NewRecorder()
+			app.requestID(next).ServeHTTP(rr, r)
+
+			assert.Equal(t, rr.Header().Get("X-Request-ID"), seen)
+			assert.Equal(t, seen == tt.header, tt.wantKept)
+			assert.Equal(t, requestIDRX.MatchString(seen), true)
+		})
+	}
+}
+
+func TestLimitBody(t *testing.T){
+	app := &application{formDecoder: form.NewDecoder()}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+		err := app.decodePostForm(r, &struct{}{})
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError){
+			app.clientError(w, http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.Write([]byte("OK"))
+	})
+
+	tests := []struct{
+		name string
+		body string
+		// -1 leaves the length unknown, as with a chunked body
+		contentLength int64
+		wantCode int
+	}{
+		{name: "Small", body: "a=1", contentLength: 3, wantCode: http.StatusOK},
+		{name: "Too big", body: "a=12345678901", contentLength: 13, wantCode: http.StatusRequestEntityTooLarge},
+		{name: "Too big, length unknown", body: "a=12345678901", contentLength: -1, wantCode: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			r, err := http.NewRequest(http.MethodPost, "/snippet/create", strings.NewReader(tt.body))
+			if err != nil{
+				t.Fatal(err)
+			}
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r.ContentLength = tt.contentLength
+
+			rr := httptest.NewRecorder()
+			app.limitBody(10)(next).ServeHTTP(rr, r)
+
+			assert.Equal(t, rr.Code, tt.wantCode)
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var errOIDCNotAllowed = errors.New("oidc: email address not allowed")
+
+// oidcClient signs users in with an OpenID Connect provider using the
+// authorization code flow with PKCE. Discovery happens on first use, and is
+// retried on the next login if the provider was unreachable.
+type oidcClient struct{
+	issuer string
+	clientID string
+	clientSecret string
+	redirectURL string
+	allowedDomains []string
+
+	mu sync.Mutex
+	provider *oidc.Provider
+}
+
+// The claims snippetbox uses from the ID token
+type oidcClaims struct{
+	Subject string		`json:"sub"`
+	Email string		`json:"email"`
+	EmailVerified bool	`json:"email_verified"`
+	Name string			`json:"name"`
+}
+
+func newOIDCClient(cfg *config) *oidcClient{
+	if cfg.OIDCIssuer == ""{
+		return nil
+	}
+
+	redirectURL := cfg.OIDCRedirectURL
+	if redirectURL == ""{
+		redirectURL = cfg.BaseURL + "/user/login/oidc/callback"
+	}
+
+	return &oidcClient{
+		issuer: cfg.OIDCIssuer,
+		clientID: cfg.OIDCClientID,
+		clientSecret: cfg.OIDCClientSecret,
+		redirectURL: redirectURL,
+		allowedDomains: cfg.OIDCAllowedDomains,
+	}
+}
+
+func (c *oidcClient) oauth2Config(ctx context.Context) (*oauth2.Config, *oidc.Provider, error){
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.provider == nil{
+		provider, err := oidc.NewProvider(ctx, c.issuer)
+		if err != nil{
+			return nil, nil, err
+		}
+		c.provider = provider
+	}
+
+	return &oauth2.Config{
+		ClientID: c.clientID,
+		ClientSecret: c.clientSecret,
+		RedirectURL: c.redirectURL,
+		Endpoint: c.provider.Endpoint(),
+		Scopes: []string{oidc.ScopeOpenID, "email", "profile"},
+	}, c.provider, nil
+}
+
+// oidcLogin holds what has to survive the round trip through the provider.
+// It is kept in the session.
+type oidcLogin struct{
+	State string
+	Nonce string
+	Verifier string
+}
+
+func newOIDCLogin() (oidcLogin, error){
+	random := func() (string, error){
+		b := make([]byte, 32)
+		_, err := rand.Read(b)
+		return base64.RawURLEncoding.EncodeToString(b), err
+	}
+
+	state, err := random()
+	if err != nil{
+		return oidcLogin{}, err
+	}
+	nonce, err := random()
+	if err != nil{
+		return oidcLogin{}, err
+	}
+
+	return oidcLogin{State: state, Nonce: nonce, Verifier: oauth2.GenerateVerifier()}, nil
+}
+
+// authCodeURL returns the provider URL to send the user to.
+func (c *oidcClient) authCodeURL(ctx context.Context, login oidcLogin) (string, error){
+	config, _, err := c.oauth2Config(ctx)
+	if err != nil{
+		return "", err
+	}
+
+	return config.AuthCodeURL(login.State, oidc.Nonce(login.Nonce), oauth2.S256ChallengeOption(login.Verifier)), nil
+}
+
+// exchange swaps the code from the callback for tokens and returns the
+// claims from the ID token, once its signature (against the provider's JWKS),
+// audience, expiry and nonce have been checked.
+func (c *oidcClient) exchange(ctx context.Context, login oidcLogin, code string) (*oidcClaims, error){
+	config, provider, err := c.oauth2Config(ctx)
+	if err != nil{
+		return nil, err
+	}
+
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(login.Verifier))
+	if err != nil{
+		return nil, err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok{
+		return nil, errors.New("oidc: no id_token in token response")
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: c.clientID}).Verify(ctx, rawIDToken)
+	if err != nil{
+		return nil, err
+	}
+	if idToken.Nonce != login.Nonce{
+		return nil, errors.New("oidc: nonce does not match")
+	}
+
+	claims := &oidcClaims{}
+	err = idToken.Claims(claims)
+	if err != nil{
+		return nil, err
+	}
+
+	// Accounts are matched by email, so it must be one the provider vouches for
+	if claims.Email == "" || !claims.EmailVerified{
+		return nil, fmt.Errorf("%w: %q is not verified by the provider", errOIDCNotAllowed, claims.Email)
+	}
+	if !c.domainAllowed(claims.Email){
+		return nil, fmt.Errorf("%w: %q is not in an allowed domain", errOIDCNotAllowed, claims.Email)
+	}
+
+	return claims, nil
+}
+
+// An empty allow-list allows every domain.
+func (c *oidcClient) domainAllowed(email string) bool{
+	if len(c.allowedDomains) == 0{
+		return true
+	}
+
+	_, domain, ok := strings.Cut(email, "@")
+	if !ok{
+		return false
+	}
+
+	for _, allowed := range c.allowedDomains{
+		if strings.EqualFold(domain, allowed){
+			return true
+		}
+	}
+	return false
+}
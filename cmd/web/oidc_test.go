@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+	"github.com/alexedwards/scs/v2"
+	"github.com/alexedwards/scs/v2/memstore"
+	"github.com/go-jose/go-jose/v4"
+)
+
+// mockIssuer is a minimal OpenID provider: discovery, JWKS, and a token
+// endpoint that answers with whatever ID token claims the test sets.
+type mockIssuer struct{
+	*httptest.Server
+	key *rsa.PrivateKey
+	claims map[string]any
+
+	// The PKCE verifier the token endpoint was given
+	verifier string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer{
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	m := &mockIssuer{key: key}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request){
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer": m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint": m.URL + "/token",
+			"jwks_uri": m.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request){
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request){
+		m.verifier = r.FormValue("code_verifier")
+
+		// As for a code that has already been used or has expired
+		if r.FormValue("code") == "used"{
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]any{"error": "invalid_grant"})
+			return
+		}
+
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key},
+			(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test"))
+		if err != nil{
+			t.Fatal(err)
+		}
+		payload, _ := json.Marshal(m.claims)
+		signed, err := signer.Sign(payload)
+		if err != nil{
+			t.Fatal(err)
+		}
+		idToken, _ := signed.CompactSerialize()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type": "Bearer",
+			"expires_in": 3600,
+			"id_token": idToken,
+		})
+	})
+
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func TestOIDCAuthCodeURL(t *testing.T){
+	issuer := newMockIssuer(t)
+
+	cfg := defaultConfig()
+	cfg.OIDCIssuer = issuer.URL
+	cfg.OIDCClientID = "snippetbox"
+	client := newOIDCClient(cfg)
+
+	login, err := newOIDCLogin()
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	authURL, err := client.authCodeURL(context.Background(), login)
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(authURL)
+	if err != nil{
+		t.Fatal(err)
+	}
+	challenge := sha256.Sum256([]byte(login.Verifier))
+
+	q := u.Query()
+	assert.Equal(t, u.Path, "/authorize")
+	assert.Equal(t, q.Get("state"), login.State)
+	assert.Equal(t, q.Get("nonce"), login.Nonce)
+	assert.Equal(t, q.Get("redirect_uri"), "https://localhost:4000/user/login/oidc/callback")
+	assert.Equal(t, q.Get("code_challenge_method"), "S256")
+	assert.Equal(t, q.Get("code_challenge"), base64.RawURLEncoding.EncodeToString(challenge[:]))
+}
+
+func TestOIDCExchange(t *testing.T){
+	issuer := newMockIssuer(t)
+
+	cfg := defaultConfig()
+	cfg.OIDCIssuer = issuer.URL
+	cfg.OIDCClientID = "snippetbox"
+	cfg.OIDCAllowedDomains = []string{"example.com"}
+	client := newOIDCClient(cfg)
+
+	login := oidcLogin{State: "state", Nonce: "nonce", Verifier: "verifier-verifier-verifier-verifier-verifier"}
+
+	claims := func(changes map[string]any) map[string]any{
+		c := map[string]any{
+			"iss": issuer.URL,
+			"aud": "snippetbox",
+			"sub": "1234",
+			"exp": time.Now().Add(time.Hour).Unix(),
+			"iat": time.Now().Unix(),
+			"nonce": "nonce",
+			"email": "alice@example.com",
+			"email_verified": true,
+			"name": "Alice",
+		}
+		for k, v := range changes{
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct{
+		name string
+		claims map[string]any
+		wantErr bool
+		wantNotAllowed bool
+	}{
+		{name: "Valid", claims: claims(nil)},
+		{name: "Nonce mismatch", claims: claims(map[string]any{"nonce": "replayed"}), wantErr: true},
+		{name: "Wrong audience", claims: claims(map[string]any{"aud": "another-app"}), wantErr: true},
+		{name: "Expired", claims: claims(map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}), wantErr: true},
+		{name: "Unverified email", claims: claims(map[string]any{"email_verified": false}), wantErr: true, wantNotAllowed: true},
+		{name: "Disallowed domain", claims: claims(map[string]any{"email": "mallory@example.org"}), wantErr: true, wantNotAllowed: true},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			issuer.claims = tt.claims
+
+			got, err := client.exchange(context.Background(), login, "code")
+			assert.Equal(t, err != nil, tt.wantErr)
+			assert.Equal(t, errors.Is(err, errOIDCNotAllowed), tt.wantNotAllowed)
+			assert.Equal(t, issuer.verifier, login.Verifier)
+
+			if !tt.wantErr{
+				assert.Equal(t, got.Subject, "1234")
+				assert.Equal(t, got.Email, "alice@example.com")
+				assert.Equal(t, got.Name, "Alice")
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackFailure(t *testing.T){
+	issuer := newMockIssuer(t)
+
+	cfg := defaultConfig()
+	cfg.OIDCIssuer = issuer.URL
+	cfg.OIDCClientID = "snippetbox"
+
+	sessionManager := scs.New()
+	sessionManager.Store = memstore.New()
+	app := &application{
+		errorLog: log.New(io.Discard, "", 0),
+		sessionManager: sessionManager,
+		oidc: newOIDCClient(cfg),
+	}
+
+	tests := []struct{
+		name string
+		query string
+	}{
+		{name: "Used code", query: "?state=state&code=used"},
+		{name: "Wrong state", query: "?state=forged&code=code"},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			// A session part way through a login, as userLoginOIDC leaves it
+			ctx, err := sessionManager.Load(context.Background(), "")
+			if err != nil{
+				t.Fatal(err)
+			}
+			sessionManager.Put(ctx, "oidcState", "state")
+			sessionManager.Put(ctx, "oidcNonce", "nonce")
+			sessionManager.Put(ctx, "oidcVerifier", "verifier-verifier-verifier-verifier-verifier")
+			token, _, err := sessionManager.Commit(ctx)
+			if err != nil{
+				t.Fatal(err)
+			}
+
+			r, err := http.NewRequest(http.MethodGet, "/user/login/oidc/callback"+tt.query, nil)
+			if err != nil{
+				t.Fatal(err)
+			}
+			r.AddCookie(&http.Cookie{Name: sessionManager.Cookie.Name, Value: token})
+
+			rr := httptest.NewRecorder()
+			sessionManager.LoadAndSave(http.HandlerFunc(app.userLoginOIDCCallback)).ServeHTTP(rr, r)
+
+			assert.Equal(t, rr.Code, http.StatusSeeOther)
+			assert.Equal(t, rr.Header().Get("Location"), "/user/login")
+
+			ctx, err = sessionManager.Load(context.Background(), token)
+			if err != nil{
+				t.Fatal(err)
+			}
+			assert.Equal(t, sessionManager.GetString(ctx, "flash"), "Sign-in failed. Please try again.")
+		})
+	}
+}
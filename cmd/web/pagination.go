@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// Rows per page in admin listings
+const pageSize = 25
+
+// Pages past this aren't worth reaching by paging, and keep the offset small
+// enough for MySQL however big a number is asked for
+const maxPage = 10000
+
+// pagination describes one page of a listing and builds the links to its
+// neighbours, keeping the search query and any other filters.
+type pagination struct{
+	Page int
+	Total int
+	Query string
+	path string
+	filters url.Values
+}
+
+// newPagination reads the page and search query from the URL. Pages are
+// numbered from 1, up to maxPage.
+func newPagination(u *url.URL) *pagination{
+	filters := u.Query()
+
+	page, err := strconv.Atoi(filters.Get("page"))
+	if err != nil || page < 1{
+		page = 1
+	}
+	page = min(page, maxPage)
+	filters.Del("page")
+
+	return &pagination{Page: page, Query: filters.Get("q"), path: u.Path, filters: filters}
+}
+
+func (p *pagination) Offset() int{
+	return (p.Page - 1) * pageSize
+}
+
+func (p *pagination) Pages() int{
+	if p.Total == 0{
+		return 1
+	}
+	return (p.Total + pageSize - 1) / pageSize
+}
+
+func (p *pagination) HasPrev() bool{
+	return p.Page > 1
+}
+
+func (p *pagination) HasNext() bool{
+	return p.Page < p.Pages()
+}
+
+func (p *pagination) PrevURL() string{
+	return p.url(p.Page - 1)
+}
+
+func (p *pagination) NextURL() string{
+	return p.url(p.Page + 1)
+}
+
+// URL is the link to this page, for returning to it after an action.
+func (p *pagination) URL() string{
+	return p.url(p.Page)
+}
+
+func (p *pagination) url(page int) string{
+	v := url.Values{}
+	for key, values := range p.filters{
+		if values[0] != ""{
+			v.Set(key, values[0])
+		}
+	}
+	if page > 1{
+		v.Set("page", strconv.Itoa(page))
+	}
+	if len(v) == 0{
+		return p.path
+	}
+	return p.path + "?" + v.Encode()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+)
+
+func TestPagination(t *testing.T){
+	tests := []struct{
+		name string
+		url string
+		total int
+		wantOffset int
+		wantPages int
+		wantPrev string
+		wantNext string
+	}{
+		{name: "First page", url: "/admin/users", total: 60, wantOffset: 0, wantPages: 3, wantNext: "/admin/users?page=2"},
+		{name: "Middle page with query", url: "/admin/users?q=a%26b&page=2", total: 60, wantOffset: 25, wantPages: 3, wantPrev: "/admin/users?q=a%26b", wantNext: "/admin/users?page=3&q=a%26b"},
+		{name: "Keeps filters", url: "/admin/audit?user=a%40example.com&from=2024-01-01&to=&page=2", total: 30, wantOffset: 25, wantPages: 2, wantPrev: "/admin/audit?from=2024-01-01&user=a%40example.com"},
+		{name: "Last page", url: "/admin/users?page=3", total: 60, wantOffset: 50, wantPages: 3, wantPrev: "/admin/users?page=2"},
+		{name: "Empty", url: "/admin/users", total: 0, wantOffset: 0, wantPages: 1},
+		{name: "Bad page", url: "/admin/users?page=-4", total: 10, wantOffset: 0, wantPages: 1},
+		{name: "Huge page", url: "/admin/users?page=9223372036854775807", total: 10, wantOffset: (maxPage - 1) * pageSize, wantPages: 1, wantPrev: "/admin/users?page=9999"},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			u, err := url.Parse(tt.url)
+			if err != nil{
+				t.Fatal(err)
+			}
+
+			p := newPagination(u)
+			p.Total = tt.total
+
+			assert.Equal(t, p.Offset(), tt.wantOffset)
+			assert.Equal(t, p.Pages(), tt.wantPages)
+			assert.Equal(t, p.HasPrev(), tt.wantPrev != "")
+			assert.Equal(t, p.HasNext(), tt.wantNext != "")
+			if p.HasPrev(){
+				assert.Equal(t, p.PrevURL(), tt.wantPrev)
+			}
+			if p.HasNext(){
+				assert.Equal(t, p.NextURL(), tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestAdminNext(t *testing.T){
+	assert.Equal(t, adminNext("/admin/users?page=2", "/admin/users"), "/admin/users?page=2")
+	assert.Equal(t, adminNext("https://evil.example.com/admin/", "/admin/users"), "/admin/users")
+	assert.Equal(t, adminNext("", "/admin/snippets"), "/admin/snippets")
+}
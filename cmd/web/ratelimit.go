@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/justinas/alice"
+	"golang.org/x/time/rate"
+)
+
+// Buckets not used for this long are dropped by the eviction loop.
+const rateLimitIdle = 10 * time.Minute
+
+// rateLimit is a limit as written in the config, e.g. "10/1m" allows bursts of
+// ten requests refilled at ten a minute.
+type rateLimit struct{
+	Requests int
+	Per time.Duration
+}
+
+func parseRateLimit(s string) (rateLimit, error){
+	n, per, ok := strings.Cut(s, "/")
+	if !ok{
+		return rateLimit{}, fmt.Errorf("%q is not a limit such as 10/1m", s)
+	}
+
+	requests, err := strconv.Atoi(n)
+	if err != nil || requests < 1{
+		return rateLimit{}, fmt.Errorf("%q is not a limit such as 10/1m", s)
+	}
+
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0{
+		return rateLimit{}, fmt.Errorf("%q is not a limit such as 10/1m", s)
+	}
+
+	return rateLimit{Requests: requests, Per: d}, nil
+}
+
+func (l rateLimit) String() string{
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// rateLimiter keeps one token bucket per client.
+type rateLimiter struct{
+	limit rate.Limit
+	burst int
+
+	mu sync.Mutex
+	clients map[string]*rateClient
+}
+
+type rateClient struct{
+	limiter *rate.Limiter
+	lastSeen time.Time
+}
+
+func newRateLimiter(l rateLimit) *rateLimiter{
+	return &rateLimiter{
+		limit: rate.Limit(float64(l.Requests) / l.Per.Seconds()),
+		burst: l.Requests,
+		clients: map[string]*rateClient{},
+	}
+}
+
+// allow takes a token from key's bucket. If it is empty, it returns how long
+// until the next token is available.
+func (l *rateLimiter) allow(key string) (bool, time.Duration){
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c, ok := l.clients[key]
+	if !ok{
+		c = &rateClient{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = c
+	}
+	c.lastSeen = time.Now()
+
+	reservation := c.limiter.Reserve()
+	delay := reservation.Delay()
+	if delay == 0{
+		return true, 0
+	}
+
+	// Hand the token back - this request is refused, not queued.
+	reservation.Cancel()
+	return false, delay
+}
+
+func (l *rateLimiter) evict(idle time.Duration){
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, c := range l.clients{
+		if time.Since(c.lastSeen) > idle{
+			delete(l.clients, key)
+		}
+	}
+}
+
+// rateLimiters holds the global limiter and one per group of routes. A nil
+// limiter means rate limiting is switched off.
+type rateLimiters struct{
+	global *rateLimiter
+	read *rateLimiter
+	signup *rateLimiter
+	login *rateLimiter
+	create *rateLimiter
+	// Password reset and confirmation emails, kept apart from signups so a
+	// burst of those can't lock anyone out of recovering their account
+	recovery *rateLimiter
+}
+
+func newRateLimiters(cfg *config) *rateLimiters{
+	if !cfg.RateLimitEnabled{
+		return &rateLimiters{}
+	}
+
+	limiters := &rateLimiters{
+		global: newRateLimiter(cfg.RateLimitGlobal),
+		read: newRateLimiter(cfg.RateLimitRead),
+		signup: newRateLimiter(cfg.RateLimitSignup),
+		login: newRateLimiter(cfg.RateLimitLogin),
+		create: newRateLimiter(cfg.RateLimitCreate),
+		recovery: newRateLimiter(cfg.RateLimitRecovery),
+	}
+
+	go func(){
+		for range time.Tick(time.Minute){
+			limiters.evict(rateLimitIdle)
+		}
+	}()
+
+	return limiters
+}
+
+func (ls *rateLimiters) evict(idle time.Duration){
+	for _, l := range []*rateLimiter{ls.global, ls.read, ls.signup, ls.login, ls.create, ls.recovery}{
+		if l != nil{
+			l.evict(idle)
+		}
+	}
+}
+
+// rateLimit returns middleware drawing from l. Authenticated users are keyed
+// by user ID, so they get the same allowance on any network; everyone else by
+// client IP. It must run after authenticate to see the user.
+func (app *application) rateLimit(l *rateLimiter) alice.Constructor{
+	return func(next http.Handler) http.Handler{
+		if l == nil{
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+			key := "ip:" + app.clientIP(r)
+			if app.isAuthenticated(r){
+				key = fmt.Sprintf("user:%d", app.sessionManager.GetInt(r.Context(), "authenticatedUserID"))
+			}
+
+			ok, retryAfter := l.allow(key)
+			if !ok{
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				app.clientError(w, http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+	"github.com/alexedwards/scs/v2"
+	"github.com/alexedwards/scs/v2/memstore"
+)
+
+func TestRateLimit(t *testing.T){
+	app := &application{
+		errorLog: log.New(io.Discard, "", 0),
+		infoLog: log.New(io.Discard, "", 0),
+	}
+	limiter := newRateLimiter(rateLimit{Requests: 2, Per: time.Minute})
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+		w.Write([]byte("OK"))
+	})
+	handler := app.rateLimit(limiter)(next)
+
+	request := func(remoteAddr string) *http.Response{
+		rr := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = remoteAddr
+		handler.ServeHTTP(rr, r)
+		return rr.Result()
+	}
+
+	assert.Equal(t, request("192.0.2.1:1234").StatusCode, http.StatusOK)
+	assert.Equal(t, request("192.0.2.1:1235").StatusCode, http.StatusOK)
+
+	rs := request("192.0.2.1:1236")
+	assert.Equal(t, rs.StatusCode, http.StatusTooManyRequests)
+	assert.Equal(t, rs.Header.Get("Retry-After"), "30")
+
+	// Another client has its own bucket
+	assert.Equal(t, request("198.51.100.7:1234").StatusCode, http.StatusOK)
+
+	limiter.evict(0)
+	assert.Equal(t, len(limiter.clients), 0)
+}
+
+func TestParseRateLimit(t *testing.T){
+	tests := []struct{
+		value string
+		want rateLimit
+		wantErr bool
+	}{
+		{value: "10/1m", want: rateLimit{Requests: 10, Per: time.Minute}},
+		{value: "100/1s", want: rateLimit{Requests: 100, Per: time.Second}},
+		{value: "10", wantErr: true},
+		{value: "0/1m", wantErr: true},
+		{value: "10/soon", wantErr: true},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.value, func(t *testing.T){
+			l, err := parseRateLimit(tt.value)
+			assert.Equal(t, err != nil, tt.wantErr)
+			assert.Equal(t, l, tt.want)
+		})
+	}
+}
+
+func TestClientIP(t *testing.T){
+	proxies, err := parseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.10"})
+	if err != nil{
+		t.Fatal(err)
+	}
+	app := &application{trustedProxies: proxies}
+
+	tests := []struct{
+		name string
+		remoteAddr string
+		forwardedFor string
+		want string
+	}{
+		{
+			name: "Direct",
+			remoteAddr: "203.0.113.5:5000",
+			want: "203.0.113.5",
+		},
+		{
+			name: "Untrusted peer",
+			remoteAddr: "203.0.113.5:5000",
+			forwardedFor: "198.51.100.7",
+			want: "203.0.113.5",
+		},
+		{
+			name: "Trusted proxy",
+			remoteAddr: "10.1.2.3:5000",
+			forwardedFor: "198.51.100.7",
+			want: "198.51.100.7",
+		},
+		{
+			name: "Chain of proxies with spoofed entry",
+			remoteAddr: "10.1.2.3:5000",
+			forwardedFor: "1.1.1.1, 198.51.100.7, 192.0.2.10",
+			want: "198.51.100.7",
+		},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != ""{
+				r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+
+			assert.Equal(t, app.clientIP(r), tt.want)
+		})
+	}
+}
+
+func TestGlobalRateLimitSkipsHealthChecks(t *testing.T){
+	sessionManager := scs.New()
+	sessionManager.Store = memstore.New()
+	app := &application{
+		errorLog: log.New(io.Discard, "", 0),
+		infoLog: log.New(io.Discard, "", 0),
+		cfg: defaultConfig(),
+		sessionManager: sessionManager,
+		rateLimiters: &rateLimiters{global: newRateLimiter(rateLimit{Requests: 1, Per: time.Minute})},
+	}
+	handler := app.routes()
+
+	request := func(path string) int{
+		rr := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		handler.ServeHTTP(rr, r)
+		return rr.Code
+	}
+
+	// Probes keep answering however often they come
+	for i := 0; i < 3; i++{
+		assert.Equal(t, request("/healthz"), http.StatusOK)
+	}
+
+	// while the rest of the site is limited. The first request gets through
+	// to the handler, which fails for want of templates.
+	assert.Equal(t, request("/user/login") != http.StatusTooManyRequests, true)
+	assert.Equal(t, request("/user/login"), http.StatusTooManyRequests)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+)
+
+// Holds "<session ID>:<token>" for a remember-me login
+const rememberCookie = "remember_me"
+
+// remember issues a remember-me token for the session the user has just
+// logged in with, if remember-me logins are on.
+func (app *application) remember(w http.ResponseWriter, r *http.Request, userID int) error{
+	if app.cfg.RememberMeLifetime == 0{
+		return nil
+	}
+
+	sessionID := app.sessionManager.GetString(r.Context(), "sessionID")
+
+	token, err := app.rememberTokens.New(sessionID, userID, app.cfg.RememberMeLifetime)
+	if err != nil{
+		return err
+	}
+
+	app.setRememberCookie(w, sessionID, token)
+	return nil
+}
+
+// resumeLogin logs the user back in with their remember-me token once their
+// session has expired, returning who they are. It returns 0 when there's no
+// token, or it isn't valid.
+//
+// The token is replaced each time. If one that has already been replaced
+// turns up, two browsers have it and there is no telling which is the
+// owner's, so the user is signed out everywhere.
+func (app *application) resumeLogin(w http.ResponseWriter, r *http.Request) (int, error){
+	if app.cfg.RememberMeLifetime == 0{
+		return 0, nil
+	}
+
+	cookie, err := r.Cookie(rememberCookie)
+	if err != nil{
+		return 0, nil
+	}
+
+	sessionID, token, ok := strings.Cut(cookie.Value, ":")
+	if !ok{
+		app.forgetRememberCookie(w)
+		return 0, nil
+	}
+
+	userID, newToken, err := app.rememberTokens.Rotate(sessionID, token)
+	if err != nil{
+		switch{
+		case errors.Is(err, models.ErrNoRecord):
+			app.forgetRememberCookie(w)
+			return 0, nil
+		case errors.Is(err, models.ErrTokenReused):
+			app.forgetRememberCookie(w)
+			app.auditAs(r, userID, models.AuditRememberTokenReused, map[string]any{"session_id": sessionID})
+			return 0, app.revokeSessions(userID, "")
+		}
+		return 0, err
+	}
+
+	user, err := app.users.Get(userID)
+	if err != nil{
+		return 0, err
+	}
+	if user.Disabled{
+		app.forgetRememberCookie(w)
+		return 0, nil
+	}
+
+	// The session record stays the same - it's the same device
+	err = app.sessionManager.RenewToken(r.Context())
+	if err != nil{
+		return 0, err
+	}
+	err = app.sessions.SetToken(sessionID, app.sessionManager.Token(r.Context()))
+	if err != nil{
+		return 0, err
+	}
+	app.sessionManager.Put(r.Context(), "authenticatedUserID", userID)
+	app.sessionManager.Put(r.Context(), "sessionID", sessionID)
+	app.auditAs(r, userID, models.AuditLogin, map[string]any{"method": "remember_me"})
+
+	if newToken != ""{
+		app.setRememberCookie(w, sessionID, newToken)
+	}
+	return userID, nil
+}
+
+func (app *application) setRememberCookie(w http.ResponseWriter, sessionID, token string){
+	http.SetCookie(w, &http.Cookie{
+		Name: rememberCookie,
+		Value: sessionID + ":" + token,
+		Path: "/",
+		MaxAge: int(app.cfg.RememberMeLifetime.Seconds()),
+		HttpOnly: true,
+		Secure: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (app *application) forgetRememberCookie(w http.ResponseWriter){
+	http.SetCookie(w, &http.Cookie{
+		Name: rememberCookie,
+		Path: "/",
+		MaxAge: -1,
+		HttpOnly: true,
+		Secure: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+)
+
+func TestResumeLoginWithoutToken(t *testing.T){
+	app := &application{cfg: defaultConfig()}
+
+	tests := []struct{
+		name string
+		cookie string
+		wantCleared bool
+	}{
+		{name: "No cookie"},
+		{name: "Malformed", cookie: "not-a-token", wantCleared: true},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			r, err := http.NewRequest(http.MethodGet, "/", nil)
+			if err != nil{
+				t.Fatal(err)
+			}
+			if tt.cookie != ""{
+				r.AddCookie(&http.Cookie{Name: rememberCookie, Value: tt.cookie})
+			}
+
+			rr := httptest.NewRecorder()
+			id, err := app.resumeLogin(rr, r)
+			if err != nil{
+				t.Fatal(err)
+			}
+			assert.Equal(t, id, 0)
+
+			cleared := false
+			for _, c := range rr.Result().Cookies(){
+				if c.Name == rememberCookie && c.MaxAge < 0{
+					cleared = true
+				}
+			}
+			assert.Equal(t, cleared, tt.wantCleared)
+		})
+	}
+}
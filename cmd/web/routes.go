@@ -3,6 +3,7 @@ package main
 import (
 	"net/http"
 
+	"github.com/AVSanjay-12/snippetbox/internal/models"
 	"github.com/julienschmidt/httprouter"
 	"github.com/justinas/alice"
 )
@@ -15,29 +16,93 @@ func (app *application) routes() http.Handler {
 		app.notFound(w)			
 	})
 
-	// File server for serving static files from disk
-	fileServer := http.FileServer(http.Dir("./ui/static/"))
-	router.Handler(http.MethodGet, "/static/*filepath", http.StripPrefix("/static", fileServer))
+	// Health checks - kept outside the session middleware and rate limits so
+	// probes never touch the session store or CSRF cookies, and are never
+	// turned away
+	router.HandlerFunc(http.MethodGet, "/healthz", app.healthz)
+	router.HandlerFunc(http.MethodGet, "/readyz", app.readyz)
 
-	dynamic := alice.New(app.sessionManager.LoadAndSave, noSurf, app.authenticate)
+	// File server for the static files, with ETags and fingerprinted URLs.
+	// Not rate limited, as a page can pull in several at once
+	router.Handler(http.MethodGet, "/static/*filepath", http.StripPrefix("/static", app.static))
+
+	// The global limit comes before authenticate, so it is always per IP
+	dynamic := alice.New(app.rateLimit(app.rateLimiters.global), app.sessionManager.LoadAndSave, noSurf, app.authenticate)
+
+	// Per-route rate limits, applied after authenticate so signed-in users
+	// are limited by user ID rather than IP
+	read := dynamic.Append(app.rateLimit(app.rateLimiters.read))
+	signup := dynamic.Append(app.rateLimit(app.rateLimiters.signup))
+	login := dynamic.Append(app.rateLimit(app.rateLimiters.login))
+	recovery := dynamic.Append(app.rateLimit(app.rateLimiters.recovery))
 
 	// Application routes
 	// router.HandlerFunc is an adapter -> Allows the usage of http.HandlerFunc
 	// as a request handle
-	router.Handler(http.MethodGet, "/", dynamic.ThenFunc(app.home))
-	router.Handler(http.MethodGet, "/snippet/view/:id", dynamic.ThenFunc(app.snippetView))
+	router.Handler(http.MethodGet, "/", read.ThenFunc(app.home))
+	router.Handler(http.MethodGet, "/snippet/view/:id", read.ThenFunc(app.snippetView))
+	// Not /snippet/:id/history - httprouter can't have a wildcard where
+	// /snippet/view and the rest have a static segment
+	router.Handler(http.MethodGet, "/snippet/history/:id", read.ThenFunc(app.snippetHistory))
+	router.Handler(http.MethodGet, "/snippet/raw/:id/:name", read.ThenFunc(app.snippetRaw))
+	router.Handler(http.MethodGet, "/snippet/zip/:id", read.ThenFunc(app.snippetZIP))
 	router.Handler(http.MethodGet, "/user/signup", dynamic.ThenFunc(app.userSignup))
-	router.Handler(http.MethodPost, "/user/signup", dynamic.ThenFunc(app.userSignupPost))
+	router.Handler(http.MethodPost, "/user/signup", signup.ThenFunc(app.userSignupPost))
 	router.Handler(http.MethodGet, "/user/login", dynamic.ThenFunc(app.userLogin))
-	router.Handler(http.MethodPost, "/user/login", dynamic.ThenFunc(app.userLoginPost))
+	router.Handler(http.MethodPost, "/user/login", login.ThenFunc(app.userLoginPost))
+	router.Handler(http.MethodGet, "/user/verify", dynamic.ThenFunc(app.userVerify))
+	router.Handler(http.MethodGet, "/user/password/forgot", dynamic.ThenFunc(app.userForgotPassword))
+	router.Handler(http.MethodPost, "/user/password/forgot", recovery.ThenFunc(app.userForgotPasswordPost))
+	router.Handler(http.MethodGet, "/user/password/reset", dynamic.ThenFunc(app.userResetPassword))
+	router.Handler(http.MethodPost, "/user/password/reset", login.ThenFunc(app.userResetPasswordPost))
+	router.Handler(http.MethodGet, "/user/login/totp", dynamic.ThenFunc(app.userLoginTOTP))
+	router.Handler(http.MethodPost, "/user/login/totp", login.ThenFunc(app.userLoginTOTPPost))
+	router.Handler(http.MethodGet, "/user/login/oidc", login.ThenFunc(app.userLoginOIDC))
+	router.Handler(http.MethodGet, "/user/login/oidc/callback", login.ThenFunc(app.userLoginOIDCCallback))
 
 	protected := dynamic.Append(app.requireAuthentication)
-	router.Handler(http.MethodGet, "/snippet/create", protected.Then(dynamic.ThenFunc(app.snippetCreate)))
-	router.Handler(http.MethodPost, "/snippet/create", protected.Then(dynamic.ThenFunc(app.snippetCreatePost)))
-	router.Handler(http.MethodPost, "/user/logout", protected.Then(dynamic.ThenFunc(app.userLogoutPost)))
+	router.Handler(http.MethodGet, "/user/verify/pending", protected.ThenFunc(app.userVerifyPending))
+	router.Handler(http.MethodPost, "/user/verify/resend", protected.Append(app.rateLimit(app.rateLimiters.recovery)).ThenFunc(app.userVerifyResendPost))
+	router.Handler(http.MethodPost, "/user/logout", protected.ThenFunc(app.userLogoutPost))
+	router.Handler(http.MethodGet, "/account/view", protected.ThenFunc(app.accountView))
+	router.Handler(http.MethodGet, "/account/password/update", protected.ThenFunc(app.accountPasswordUpdate))
+	router.Handler(http.MethodPost, "/account/password/update", protected.Append(app.rateLimit(app.rateLimiters.login)).ThenFunc(app.accountPasswordUpdatePost))
+	router.Handler(http.MethodGet, "/account/sessions", protected.ThenFunc(app.accountSessions))
+	router.Handler(http.MethodPost, "/account/sessions/revoke", protected.ThenFunc(app.accountSessionRevokePost))
+	router.Handler(http.MethodPost, "/account/sessions/revoke-others", protected.ThenFunc(app.accountSessionsRevokeOthersPost))
+	router.Handler(http.MethodGet, "/account/export", protected.ThenFunc(app.accountExport))
+	router.Handler(http.MethodGet, "/account/delete", protected.ThenFunc(app.accountDelete))
+	router.Handler(http.MethodPost, "/account/delete", protected.Append(app.rateLimit(app.rateLimiters.login)).ThenFunc(app.accountDeletePost))
+	router.Handler(http.MethodGet, "/account/2fa", protected.ThenFunc(app.accountTOTP))
+	router.Handler(http.MethodPost, "/account/2fa/setup", protected.ThenFunc(app.accountTOTPSetupPost))
+	router.Handler(http.MethodGet, "/account/2fa/enable", protected.ThenFunc(app.accountTOTPEnable))
+	router.Handler(http.MethodGet, "/account/2fa/qr.png", protected.ThenFunc(app.accountTOTPQRCode))
+	router.Handler(http.MethodPost, "/account/2fa/enable", protected.Append(app.rateLimit(app.rateLimiters.login)).ThenFunc(app.accountTOTPEnablePost))
+	router.Handler(http.MethodPost, "/account/2fa/disable", protected.Append(app.rateLimit(app.rateLimiters.login)).ThenFunc(app.accountTOTPDisablePost))
+
+	router.Handler(http.MethodPost, "/snippet/delete/:id", protected.ThenFunc(app.snippetDeletePost))
+
+	admin := protected.Append(app.requireRole(models.RoleAdmin))
+	router.Handler(http.MethodGet, "/admin", admin.ThenFunc(app.adminDashboard))
+	router.Handler(http.MethodGet, "/admin/users", admin.ThenFunc(app.adminUsers))
+	router.Handler(http.MethodPost, "/admin/users/:id/role", admin.ThenFunc(app.adminUserRolePost))
+	router.Handler(http.MethodPost, "/admin/users/:id/disable", admin.ThenFunc(app.adminUserDisablePost))
+	router.Handler(http.MethodPost, "/admin/users/:id/enable", admin.ThenFunc(app.adminUserEnablePost))
+	router.Handler(http.MethodGet, "/admin/snippets", admin.ThenFunc(app.adminSnippets))
+	router.Handler(http.MethodPost, "/admin/snippets/:id/expire", admin.ThenFunc(app.adminSnippetExpirePost))
+	router.Handler(http.MethodGet, "/admin/audit", admin.ThenFunc(app.adminAudit))
+
+	verified := protected.Append(app.requireVerifiedEmail)
+	router.Handler(http.MethodGet, "/snippet/create", verified.ThenFunc(app.snippetCreate))
+	// The body limit has to come before noSurf parses the form
+	upload := alice.New(app.limitBody(int64(app.cfg.UploadMaxSize) * 1024)).Extend(verified)
+	router.Handler(http.MethodPost, "/snippet/create", upload.Append(app.rateLimit(app.rateLimiters.create)).ThenFunc(app.snippetCreatePost))
+	router.Handler(http.MethodGet, "/snippet/edit/:id", verified.ThenFunc(app.snippetEdit))
+	router.Handler(http.MethodPost, "/snippet/edit/:id", verified.Append(app.rateLimit(app.rateLimiters.create)).ThenFunc(app.snippetEditPost))
+	router.Handler(http.MethodPost, "/snippet/history/:id/restore", verified.ThenFunc(app.snippetRestorePost))
 
 	// Middleware chaining
-	standard := alice.New(app.recoverPanic, app.logRequest, secureHeaders)
+	standard := alice.New(app.recoverPanic, app.requestID, app.logRequest, secureHeaders)
 
 	return standard.Then(router)
 }
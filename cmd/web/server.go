@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// serve starts the main listener in the configured TLS mode, plus the optional
+// HTTP redirect listener, and runs them until SIGINT or SIGTERM is received.
+func (app *application) serve() error{
+	srv := &http.Server{
+		Addr: app.cfg.Addr,
+		ErrorLog: app.errorLog,
+		Handler: app.routes(),
+		IdleTimeout: app.cfg.IdleTimeout,
+		ReadTimeout: app.cfg.ReadTimeout,
+		WriteTimeout: app.cfg.WriteTimeout,
+	}
+
+	var listen func() error
+	redirect := redirectToHTTPS(app.cfg.Addr)
+
+	switch app.cfg.TLSMode{
+	case tlsModeOff:
+		listen = srv.ListenAndServe
+	case tlsModeACME:
+		manager, err := newACMEManager(app.cfg)
+		if err != nil{
+			return err
+		}
+
+		srv.TLSConfig = manager.TLSConfig()
+		srv.TLSConfig.CurvePreferences = newTLSConfig().CurvePreferences
+		redirect = manager.HTTPHandler(redirect)
+		listen = func() error{
+			return srv.ListenAndServeTLS("", "")
+		}
+	default:
+		certs, err := newCertReloader(app.cfg.TLSCertFile, app.cfg.TLSKeyFile)
+		if err != nil{
+			return err
+		}
+		app.certs = certs
+
+		srv.TLSConfig = newTLSConfig()
+		srv.TLSConfig.GetCertificate = certs.GetCertificate
+		listen = func() error{
+			return srv.ListenAndServeTLS("", "")
+		}
+	}
+
+	servers := []*http.Server{srv}
+	errCh := make(chan error, 2)
+
+	if app.cfg.RedirectAddr != "" && app.cfg.TLSMode != tlsModeOff{
+		redirectSrv := &http.Server{
+			Addr: app.cfg.RedirectAddr,
+			ErrorLog: app.errorLog,
+			Handler: redirect,
+			IdleTimeout: app.cfg.IdleTimeout,
+			ReadTimeout: app.cfg.ReadTimeout,
+			WriteTimeout: app.cfg.WriteTimeout,
+		}
+		servers = append(servers, redirectSrv)
+
+		app.infoLog.Printf("Starting HTTP redirect listener on %s", redirectSrv.Addr)
+		go func(){
+			errCh <- redirectSrv.ListenAndServe()
+		}()
+	}
+
+	app.infoLog.Printf("Starting server on %s (tls mode %s)", srv.Addr, app.cfg.TLSMode)
+	go func(){
+		errCh <- listen()
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for running := true; running;{
+		select{
+		case err := <-errCh:
+			// A listener failed to start - take the others down with it.
+			for _, s := range servers{
+				s.Close()
+			}
+			return err
+		case <-hup:
+			app.reload()
+		case s := <-quit:
+			app.infoLog.Printf("Caught signal %s, draining traffic", s)
+			running = false
+		}
+	}
+
+	// Fail readiness first, so load balancers notice and stop routing new
+	// requests here before the listeners close.
+	app.shuttingDown.Store(true)
+	time.Sleep(app.cfg.ShutdownDrainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), app.cfg.ShutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, s := range servers{
+		err := s.Shutdown(ctx)
+		if err != nil{
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil{
+		return err
+	}
+
+	// Let background jobs such as sending email finish
+	app.wg.Wait()
+
+	app.infoLog.Printf("Stopped server on %s", srv.Addr)
+	return nil
+}
+
+// reload swaps in freshly parsed templates when they come from ui_dir and, in
+// file TLS mode, the certificate on disk. Whatever fails to load is logged and
+// the version already in use keeps serving.
+func (app *application) reload(){
+	// Embedded templates are the ones the binary was built with
+	if app.cfg.UIDir == ""{
+		app.infoLog.Print("Reloading certificates; templates are embedded, set ui_dir to reload them too")
+	} else{
+		app.infoLog.Print("Reloading templates and certificates")
+
+		err := app.reloadTemplates()
+		if err != nil{
+			app.errorLog.Printf("template reload failed, keeping previous templates: %s", err)
+		}
+	}
+
+	if app.certs != nil{
+		err := app.certs.reload()
+		if err != nil{
+			app.errorLog.Printf("certificate reload failed, keeping previous certificate: %s", err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Length of the content hash used in fingerprinted URLs
+const fingerprintLen = 12
+
+// staticFiles serves ui/static with strong ETags. Templates link to assets
+// through the "static" function, which appends a fingerprint of the content,
+// so those URLs can be cached for a year - a change gives a new URL.
+type staticFiles struct{
+	fsys fs.FS
+	// Hashes are only cached for an immutable (embedded) file system; files
+	// from -ui-dir may be edited at any time.
+	cache bool
+
+	mu sync.Mutex
+	hashes map[string]string
+}
+
+func newStaticFiles(fsys fs.FS, cache bool) *staticFiles{
+	return &staticFiles{
+		fsys: fsys,
+		cache: cache,
+		hashes: map[string]string{},
+	}
+}
+
+func (s *staticFiles) hash(name string) (string, error){
+	if s.cache{
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if h, ok := s.hashes[name]; ok{
+			return h, nil
+		}
+	}
+
+	b, err := fs.ReadFile(s.fsys, name)
+	if err != nil{
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+	h := hex.EncodeToString(sum[:])
+
+	if s.cache{
+		s.hashes[name] = h
+	}
+	return h, nil
+}
+
+// url returns the fingerprinted URL for an asset, e.g.
+// /static/css/main.css?v=3f2a9c1b7d4e
+func (s *staticFiles) url(name string) string{
+	u := "/static/" + name
+
+	h, err := s.hash(name)
+	if err != nil{
+		return u
+	}
+	return u + "?v=" + h[:fingerprintLen]
+}
+
+func (s *staticFiles) ServeHTTP(w http.ResponseWriter, r *http.Request){
+	name := strings.TrimPrefix(r.URL.Path, "/")
+
+	h, err := s.hash(name)
+	if err == nil{
+		w.Header().Set("ETag", `"`+h+`"`)
+
+		if r.URL.Query().Get("v") == h[:fingerprintLen]{
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else{
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+	}
+
+	// The file server answers If-None-Match using the ETag set above.
+	http.FileServer(http.FS(s.fsys)).ServeHTTP(w, r)
+}
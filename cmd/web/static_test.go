@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+)
+
+func TestStaticFiles(t *testing.T){
+	static := newStaticFiles(fstest.MapFS{
+		"css/main.css": {Data: []byte("body { color: black; }")},
+	}, true)
+
+	url := static.url("css/main.css")
+	assert.Equal(t, strings.HasPrefix(url, "/static/css/main.css?v="), true)
+
+	tests := []struct{
+		name string
+		target string
+		ifNoneMatch string
+		wantCode int
+		wantCacheControl string
+	}{
+		{
+			name: "Fingerprinted",
+			target: strings.TrimPrefix(url, "/static"),
+			wantCode: http.StatusOK,
+			wantCacheControl: "public, max-age=31536000, immutable",
+		},
+		{
+			name: "Stale fingerprint",
+			target: "/css/main.css?v=000000000000",
+			wantCode: http.StatusOK,
+			wantCacheControl: "no-cache",
+		},
+		{
+			name: "Revalidate",
+			target: "/css/main.css",
+			ifNoneMatch: `"` + mustHash(t, static, "css/main.css") + `"`,
+			wantCode: http.StatusNotModified,
+			wantCacheControl: "no-cache",
+		},
+		{
+			name: "Missing",
+			target: "/css/missing.css",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.ifNoneMatch != ""{
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+
+			static.ServeHTTP(rr, r)
+
+			assert.Equal(t, rr.Code, tt.wantCode)
+			assert.Equal(t, rr.Header().Get("Cache-Control"), tt.wantCacheControl)
+		})
+	}
+}
+
+func mustHash(t *testing.T, static *staticFiles, name string) string{
+	t.Helper()
+
+	h, err := static.hash(name)
+	if err != nil{
+		t.Fatal(err)
+	}
+	return h
+}
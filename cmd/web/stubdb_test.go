@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// stubDB stands in for MySQL in handler tests. A query gets the rows of a key
+// it contains, or none; statements run with Exec are recorded and affect one
+// row.
+type stubDB struct{
+	mu sync.Mutex
+	rows map[string][][]driver.Value
+	execs []string
+}
+
+func newStubDB(rows map[string][][]driver.Value) (*sql.DB, *stubDB){
+	s := &stubDB{rows: rows}
+	return sql.OpenDB(s), s
+}
+
+// executed reports whether an Exec'd statement contained substr.
+func (s *stubDB) executed(substr string) bool{
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stmt := range s.execs{
+		if strings.Contains(stmt, substr){
+			return true
+		}
+	}
+	return false
+}
+
+// newSession stores a session holding values and returns its token, for a
+// request to carry in its cookie.
+func newSession(t *testing.T, sessionManager *scs.SessionManager, values map[string]any) string{
+	ctx, err := sessionManager.Load(context.Background(), "")
+	if err != nil{
+		t.Fatal(err)
+	}
+	for key, value := range values{
+		sessionManager.Put(ctx, key, value)
+	}
+
+	token, _, err := sessionManager.Commit(ctx)
+	if err != nil{
+		t.Fatal(err)
+	}
+	return token
+}
+
+// userRow is a users row in the order userColumns selects it.
+func userRow(id int, name, email string, totpEnabled bool) []driver.Value{
+	return []driver.Value{int64(id), name, email, time.Now(), true, totpEnabled, "member", false}
+}
+
+func (s *stubDB) Connect(context.Context) (driver.Conn, error){ return stubConn{s}, nil }
+func (s *stubDB) Driver() driver.Driver{ return nil }
+
+type stubConn struct{ db *stubDB }
+
+func (c stubConn) Prepare(query string) (driver.Stmt, error){ return stubStmt{c.db, query}, nil }
+func (c stubConn) Close() error{ return nil }
+func (c stubConn) Begin() (driver.Tx, error){ return stubTx{}, nil }
+
+type stubTx struct{}
+
+func (stubTx) Commit() error{ return nil }
+func (stubTx) Rollback() error{ return nil }
+
+type stubStmt struct{
+	db *stubDB
+	query string
+}
+
+func (s stubStmt) Close() error{ return nil }
+func (s stubStmt) NumInput() int{ return -1 }
+
+func (s stubStmt) Exec([]driver.Value) (driver.Result, error){
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	s.db.execs = append(s.db.execs, s.query)
+	return driver.RowsAffected(1), nil
+}
+
+func (s stubStmt) Query([]driver.Value) (driver.Rows, error){
+	for key, rows := range s.db.rows{
+		if strings.Contains(s.query, key){
+			return &stubRows{rows: rows}, nil
+		}
+	}
+	return &stubRows{}, nil
+}
+
+type stubRows struct{
+	rows [][]driver.Value
+}
+
+func (r *stubRows) Columns() []string{
+	if len(r.rows) == 0{
+		return nil
+	}
+	return make([]string, len(r.rows[0]))
+}
+
+func (r *stubRows) Close() error{ return nil }
+
+func (r *stubRows) Next(dest []driver.Value) error{
+	if len(r.rows) == 0{
+		return io.EOF
+	}
+	if len(dest) != len(r.rows[0]){
+		return errors.New("stubdb: wrong number of columns")
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"io/fs"
 	"path/filepath"
 	"text/template"
 	"time"
@@ -13,10 +15,34 @@ type templateData struct{
 	CurrentYear int
 	Snippet *models.Snippet
 	Snippets []*models.Snippet
+	// The snippet shown was forked from Parent, and Forks from it
+	Parent *models.Snippet
+	Forks []*models.Snippet
+	User *models.User
+	Users []*models.User
+	Stats *models.Stats
+	AuditEntries []*models.AuditEntry
+	Sessions []*models.UserSession
+	Revisions []*models.SnippetRevision
+	Diff *revisionDiff
+	Page *pagination
 	Form any
 	Flash string
 	IsAuthenticated bool
+	IsVerified bool
+	IsAdmin bool
+	// The signed-in user owns the snippet shown
+	IsOwner bool
 	CSRFToken string
+	TOTPSecret string
+	TOTPURI string
+	RecoveryCodes []string
+	OIDCName string
+	// The signed-in user came through single sign-on, so may not know their
+	// password here
+	SingleSignOn bool
+	// Remember-me logins are on
+	RememberMe bool
 }
 
 func humanDate(t time.Time) string{
@@ -30,37 +56,68 @@ func humanDate(t time.Time) string{
 
 var functions = template.FuncMap{
 	"humanDate": humanDate,
+	"languages": func() []language{ return languages },
+	"languageName": languageName,
+	"maxSnippetFiles": func() int{ return maxSnippetFiles },
 }
 
-func newTemplateCache() (map[string]*template.Template, error){
+func newTemplateCache(ui fs.FS, static *staticFiles) (map[string]*template.Template, error){
 	cache := map[string]*template.Template{}
 
-	pages, err := filepath.Glob("./ui/html/pages/*.html")
+	pages, err := fs.Glob(ui, "html/pages/*.html")
 	if err != nil{
 		return nil, err
 	}
 
 	for _, page := range pages{
 		name := filepath.Base(page)
-		// The template.FuncMap must be registered with the ts
-		// First Parse the base template file into a template set (ts)
-		// Add partials to ts
-		// Add page template to ts
-		ts, err := template.New(name).Funcs(functions).ParseFiles("./ui/html/base.html")
-		if err != nil{
-			return nil, err
-		}
 
-		ts, err = ts.ParseGlob("./ui/html/partials/*.html")
-		if err != nil{
-			return nil, err
+		patterns := []string{
+			"html/base.html",
+			"html/partials/*.html",
+			page,
 		}
 
-		ts, err = ts.ParseFiles(page)
+		// The template.FuncMap must be registered with the ts before parsing
+		// Parse the base template, partials and page template into a template set (ts)
+		ts, err := template.New(name).Funcs(functions).Funcs(template.FuncMap{
+			"static": static.url,
+		}).ParseFS(ui, patterns...)
 		if err != nil{
 			return nil, err
 		}
+
 		cache[name] = ts
 	}
 	return cache, nil
-}
\ No newline at end of file
+}
+
+// templates returns the current template cache. In dev mode the templates are
+// parsed from disk on every call, so edits show up on the next request; if
+// they don't parse, the last good cache is used instead.
+func (app *application) templates() (map[string]*template.Template, error){
+	if app.cfg != nil && app.cfg.Dev{
+		err := app.reloadTemplates()
+		if err != nil{
+			app.errorLog.Printf("template reload failed, keeping previous templates: %s", err)
+		}
+	}
+
+	cache := app.templateCache.Load()
+	if cache == nil{
+		return nil, errors.New("template cache not loaded")
+	}
+	return *cache, nil
+}
+
+// reloadTemplates parses the templates again and swaps them in. On a parse
+// error the cache in use is left as it was.
+func (app *application) reloadTemplates() error{
+	cache, err := newTemplateCache(app.ui, app.static)
+	if err != nil{
+		return err
+	}
+
+	app.templateCache.Store(&cache)
+	return nil
+}
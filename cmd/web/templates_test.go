@@ -1,10 +1,15 @@
 package main
 
 import (
+	"bytes"
+	"io/fs"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/AVSanjay-12/snippetbox/internal/assert"
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+	"github.com/AVSanjay-12/snippetbox/ui"
 )
 func TestHumanDate(t *testing.T) {
 	// Initialize a new time.Time object and pass it to the humanDate function.
@@ -37,3 +42,114 @@ func TestHumanDate(t *testing.T) {
 	}
 
 }
+
+func TestNewTemplateCache(t *testing.T){
+	staticFS, err := fs.Sub(ui.Files, "static")
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	cache, err := newTemplateCache(ui.Files, newStaticFiles(staticFS, true))
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	for _, page := range []string{"home.html", "view.html", "create.html", "signup.html", "login.html", "admin.html", "admin_users.html", "admin_snippets.html", "admin_audit.html", "sessions.html", "account_delete.html", "edit.html", "history.html"}{
+		_, ok := cache[page]
+		assert.Equal(t, ok, true)
+	}
+}
+
+func TestHistoryTemplate(t *testing.T){
+	staticFS, err := fs.Sub(ui.Files, "static")
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	cache, err := newTemplateCache(ui.Files, newStaticFiles(staticFS, true))
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	from := &models.SnippetRevision{Number: 1, Title: "Old", Files: []models.SnippetFile{{Name: "a.txt", Content: "a\n<b>\n"}, {Name: "gone.txt", Content: "x"}}}
+	to := &models.SnippetRevision{Number: 2, Title: "New", Files: []models.SnippetFile{{Name: "a.txt", Content: "a\n<i>\n"}}, RestoredFrom: 1}
+	data := &templateData{
+		Snippet: &models.Snippet{ID: 4, Title: "New"},
+		Revisions: []*models.SnippetRevision{to, from},
+		Diff: &revisionDiff{From: from, To: to, Files: diffFiles(from.Files, to.Files)},
+		IsOwner: true,
+	}
+
+	var buf bytes.Buffer
+	err = cache["history.html"].ExecuteTemplate(&buf, "base", data)
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	body := buf.String()
+	assert.Equal(t, strings.Contains(body, `<span class="diff-delete">-&lt;b&gt;</span>`), true)
+	assert.Equal(t, strings.Contains(body, `<span class="diff-insert">+&lt;i&gt;</span>`), true)
+	assert.Equal(t, strings.Contains(body, `(restored #1)`), true)
+	assert.Equal(t, strings.Contains(body, `value="1">Restore</button>`), true)
+	assert.Equal(t, strings.Contains(body, `gone.txt <small>(removed)</small>`), true)
+}
+
+func TestViewTemplateLineage(t *testing.T){
+	staticFS, err := fs.Sub(ui.Files, "static")
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	cache, err := newTemplateCache(ui.Files, newStaticFiles(staticFS, true))
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	data := &templateData{
+		Snippet: &models.Snippet{ID: 5, Title: "Fork", ParentID: 2, Files: []models.SnippetFile{{Name: "Dockerfile", Language: "dockerfile", Content: "FROM <scratch>"}}},
+		Parent: &models.Snippet{ID: 2, Title: "Source"},
+		Forks: []*models.Snippet{{ID: 9, Title: "<Fork of fork>", ParentID: 5}},
+		IsAuthenticated: true,
+	}
+
+	var buf bytes.Buffer
+	err = cache["view.html"].ExecuteTemplate(&buf, "base", data)
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	body := buf.String()
+	assert.Equal(t, strings.Contains(body, `Forked from <a href="/snippet/view/2">#2</a>`), true)
+	assert.Equal(t, strings.Contains(body, `<a href="/snippet/create?fork=5">Fork</a>`), true)
+	assert.Equal(t, strings.Contains(body, `&lt;Fork of fork&gt;`), true)
+	assert.Equal(t, strings.Contains(body, `<div class="file" id="file-Dockerfile">`), true)
+	assert.Equal(t, strings.Contains(body, `<a href="/snippet/raw/5/Dockerfile">Raw</a>`), true)
+	assert.Equal(t, strings.Contains(body, `<code class="language-dockerfile">FROM &lt;scratch&gt;</code>`), true)
+}
+
+func TestCreateTemplateFiles(t *testing.T){
+	staticFS, err := fs.Sub(ui.Files, "static")
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	cache, err := newTemplateCache(ui.Files, newStaticFiles(staticFS, true))
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	form := snippetCreateForm{snippetFilesForm: newSnippetFilesForm("Two files", []models.SnippetFile{{Name: "a.go", Language: "go"}, {Name: "b.txt"}}), Expires: 7}
+	form.AddFieldErrors("files.1.content", "This field cannot be empty")
+
+	var buf bytes.Buffer
+	err = cache["create.html"].ExecuteTemplate(&buf, "base", &templateData{Form: form})
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	body := buf.String()
+	assert.Equal(t, strings.Contains(body, `name="files[1].content"`), true)
+	assert.Equal(t, strings.Contains(body, `<option value="go" selected>Go</option>`), true)
+	assert.Equal(t, strings.Contains(body, `<button name="remove_file" value="1">`), true)
+	assert.Equal(t, strings.Count(body, "This field cannot be empty"), 1)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+)
+
+// loginThrottle applies exponential backoff to repeated failed logins, both
+// per account and per client IP. Once a key reaches its threshold it is locked
+// for lockoutBase, and each further failure doubles that, up to lockoutMax.
+type loginThrottle struct{
+	store models.LoginAttemptStore
+	accountThreshold int
+	ipThreshold int
+	lockoutBase time.Duration
+	lockoutMax time.Duration
+	window time.Duration
+}
+
+func accountKey(email string) string{
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string{
+	return "ip:" + ip
+}
+
+// lockedUntil returns when a key with these attempts may try again. A zero
+// time means it isn't locked.
+func (t *loginThrottle) lockedUntil(a models.LoginAttempts, threshold int) time.Time{
+	if a.Failures < threshold || time.Since(a.LastFailure) > t.window{
+		return time.Time{}
+	}
+
+	delay := t.lockoutBase
+	for i := threshold; i < a.Failures && delay < t.lockoutMax; i++{
+		delay *= 2
+	}
+	if delay > t.lockoutMax{
+		delay = t.lockoutMax
+	}
+
+	return a.LastFailure.Add(delay)
+}
+
+// check reports whether a login for this email from this IP must be refused
+// without looking at the password.
+func (t *loginThrottle) check(email, ip string) (bool, error){
+	account, err := t.store.Get(accountKey(email))
+	if err != nil{
+		return false, err
+	}
+	client, err := t.store.Get(ipKey(ip))
+	if err != nil{
+		return false, err
+	}
+
+	now := time.Now()
+	locked := t.lockedUntil(account, t.accountThreshold).After(now) ||
+		t.lockedUntil(client, t.ipThreshold).After(now)
+
+	return locked, nil
+}
+
+func (t *loginThrottle) fail(email, ip string) error{
+	_, err := t.store.RecordFailure(accountKey(email), t.window)
+	if err != nil{
+		return err
+	}
+
+	_, err = t.store.RecordFailure(ipKey(ip), t.window)
+	return err
+}
+
+// succeed clears the account's failures and returns how many there were, so
+// the user can be told about them. The IP count is left to expire, otherwise
+// logging into one's own account would reset it.
+func (t *loginThrottle) succeed(email string) (int, error){
+	key := accountKey(email)
+
+	a, err := t.store.Get(key)
+	if err != nil{
+		return 0, err
+	}
+
+	err = t.store.Reset(key)
+	if err != nil{
+		return 0, err
+	}
+
+	if time.Since(a.LastFailure) > t.window{
+		return 0, nil
+	}
+	return a.Failures, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+)
+
+func newTestThrottle() *loginThrottle{
+	return &loginThrottle{
+		store: models.NewMemoryLoginAttempts(),
+		accountThreshold: 3,
+		ipThreshold: 10,
+		lockoutBase: time.Minute,
+		lockoutMax: 10 * time.Minute,
+		window: time.Hour,
+	}
+}
+
+func TestLoginThrottleLockedUntil(t *testing.T){
+	throttle := newTestThrottle()
+	last := time.Now()
+
+	tests := []struct{
+		name string
+		failures int
+		want time.Duration
+	}{
+		{name: "Below threshold", failures: 2, want: 0},
+		{name: "At threshold", failures: 3, want: time.Minute},
+		{name: "Doubles", failures: 5, want: 4 * time.Minute},
+		{name: "Capped", failures: 20, want: 10 * time.Minute},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			until := throttle.lockedUntil(models.LoginAttempts{Failures: tt.failures, LastFailure: last}, 3)
+			if tt.want == 0{
+				assert.Equal(t, until.IsZero(), true)
+				return
+			}
+			assert.Equal(t, until.Sub(last), tt.want)
+		})
+	}
+}
+
+func TestLoginThrottle(t *testing.T){
+	throttle := newTestThrottle()
+
+	for i := 0; i < 3; i++{
+		locked, err := throttle.check("Alice@Example.com", "192.0.2.1")
+		if err != nil{
+			t.Fatal(err)
+		}
+		assert.Equal(t, locked, false)
+
+		err = throttle.fail("alice@example.com", "192.0.2.1")
+		if err != nil{
+			t.Fatal(err)
+		}
+	}
+
+	// The account is locked whatever the case of the email or the client IP
+	locked, err := throttle.check("ALICE@example.com", "198.51.100.7")
+	if err != nil{
+		t.Fatal(err)
+	}
+	assert.Equal(t, locked, true)
+
+	// Other accounts from the same IP are still allowed
+	locked, err = throttle.check("bob@example.com", "192.0.2.1")
+	if err != nil{
+		t.Fatal(err)
+	}
+	assert.Equal(t, locked, false)
+
+	failures, err := throttle.succeed("alice@example.com")
+	if err != nil{
+		t.Fatal(err)
+	}
+	assert.Equal(t, failures, 3)
+
+	locked, err = throttle.check("alice@example.com", "198.51.100.7")
+	if err != nil{
+		t.Fatal(err)
+	}
+	assert.Equal(t, locked, false)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Values for the tls_mode setting
+const (
+	tlsModeFile = "file"	// certificate and key files on disk
+	tlsModeACME = "acme"	// certificates obtained and renewed over ACME
+	tlsModeOff = "off"		// plain HTTP, for running behind a TLS-terminating proxy
+)
+
+func newTLSConfig() *tls.Config{
+	return &tls.Config{
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+	}
+}
+
+// newACMEManager returns an autocert manager restricted to the configured
+// hosts. The manager's TLS config answers TLS-ALPN-01 challenges itself and
+// its HTTPHandler answers HTTP-01 challenges on the redirect listener.
+func newACMEManager(cfg *config) (*autocert.Manager, error){
+	client := &acme.Client{DirectoryURL: cfg.ACMEDirectoryURL}
+
+	// A private CA such as Pebble serves its directory with a certificate the
+	// system pool doesn't trust.
+	if cfg.ACMECAFile != ""{
+		pem, err := os.ReadFile(cfg.ACMECAFile)
+		if err != nil{
+			return nil, err
+		}
+
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem){
+			return nil, fmt.Errorf("no certificates found in %s", cfg.ACMECAFile)
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+		client.HTTPClient = &http.Client{Transport: transport}
+	}
+
+	return &autocert.Manager{
+		Prompt: autocert.AcceptTOS,
+		Cache: autocert.DirCache(cfg.ACMECacheDir),
+		HostPolicy: autocert.HostWhitelist(cfg.ACMEHosts...),
+		Email: cfg.ACMEEmail,
+		Client: client,
+	}, nil
+}
+
+// redirectToHTTPS sends every request to the same host and path on the HTTPS
+// listener. 308 keeps the method, so a POST stays a POST.
+func redirectToHTTPS(httpsAddr string) http.Handler{
+	_, port, _ := net.SplitHostPort(httpsAddr)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil{
+			host = h
+		}
+		if host == ""{
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		if port != "" && port != "443"{
+			host = net.JoinHostPort(host, port)
+		}
+
+		w.Header().Set("Connection", "close")
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
+
+// certReloader serves the certificate from disk through GetCertificate, so a
+// rotated certificate can be swapped in without restarting.
+type certReloader struct{
+	certFile string
+	keyFile string
+	cert atomic.Pointer[tls.Certificate]
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error){
+	c := &certReloader{certFile: certFile, keyFile: keyFile}
+
+	err := c.reload()
+	if err != nil{
+		return nil, err
+	}
+	return c, nil
+}
+
+// reload reads the files again. If they don't form a valid key pair the
+// certificate in use is kept.
+func (c *certReloader) reload() error{
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil{
+		return err
+	}
+
+	c.cert.Store(&cert)
+	return nil
+}
+
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error){
+	return c.cert.Load(), nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+	"golang.org/x/crypto/acme"
+)
+
+func TestRedirectToHTTPS(t *testing.T){
+	tests := []struct{
+		name string
+		httpsAddr string
+		target string
+		want string
+	}{
+		{
+			name: "Default port",
+			httpsAddr: ":443",
+			target: "http://snippets.example.com/snippet/view/1?x=y",
+			want: "https://snippets.example.com/snippet/view/1?x=y",
+		},
+		{
+			name: "Custom port",
+			httpsAddr: ":4000",
+			target: "http://snippets.example.com:8080/",
+			want: "https://snippets.example.com:4000/",
+		},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, tt.target, nil)
+
+			redirectToHTTPS(tt.httpsAddr).ServeHTTP(rr, r)
+
+			assert.Equal(t, rr.Code, http.StatusPermanentRedirect)
+			assert.Equal(t, rr.Header().Get("Location"), tt.want)
+		})
+	}
+}
+
+// Runs against a local ACME server such as Pebble:
+//
+//	pebble -config ./test/config/pebble-config.json
+//	SNIPPETBOX_TEST_ACME_DIRECTORY=https://localhost:14000/dir \
+//	SNIPPETBOX_TEST_ACME_CA_FILE=./test/certs/pebble.minica.pem go test ./cmd/web -run ACME
+func TestACMEClient(t *testing.T){
+	directory := os.Getenv("SNIPPETBOX_TEST_ACME_DIRECTORY")
+	if directory == ""{
+		t.Skip("SNIPPETBOX_TEST_ACME_DIRECTORY not set")
+	}
+
+	cfg := defaultConfig()
+	cfg.TLSMode = tlsModeACME
+	cfg.ACMEHosts = []string{"localhost"}
+	cfg.ACMECacheDir = t.TempDir()
+	cfg.ACMEDirectoryURL = directory
+	cfg.ACMECAFile = os.Getenv("SNIPPETBOX_TEST_ACME_CA_FILE")
+
+	manager, err := newACMEManager(cfg)
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	// autocert creates the account key lazily; registering directly needs one.
+	manager.Client.Key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err = manager.Client.Register(ctx, &acme.Account{}, acme.AcceptTOS)
+	if err != nil{
+		t.Fatal(err)
+	}
+}
+
+// writeTestCert writes a self-signed certificate and key for commonName.
+func writeTestCert(t *testing.T, certFile, keyFile, commonName string){
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{CommonName: commonName},
+		NotBefore: time.Now(),
+		NotAfter: time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil{
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err != nil{
+		t.Fatal(err)
+	}
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	if err != nil{
+		t.Fatal(err)
+	}
+}
+
+func TestCertReloader(t *testing.T){
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	writeTestCert(t, certFile, keyFile, "first")
+
+	certs, err := newCertReloader(certFile, keyFile)
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	commonName := func() string{
+		cert, err := certs.GetCertificate(nil)
+		if err != nil{
+			t.Fatal(err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil{
+			t.Fatal(err)
+		}
+		return leaf.Subject.CommonName
+	}
+
+	assert.Equal(t, commonName(), "first")
+
+	// A rotated certificate is picked up on reload
+	writeTestCert(t, certFile, keyFile, "second")
+	err = certs.reload()
+	if err != nil{
+		t.Fatal(err)
+	}
+	assert.Equal(t, commonName(), "second")
+
+	// A broken one is rejected and the previous certificate kept
+	err = os.WriteFile(certFile, []byte("not a certificate"), 0600)
+	if err != nil{
+		t.Fatal(err)
+	}
+	err = certs.reload()
+	assert.Equal(t, err != nil, true)
+	assert.Equal(t, commonName(), "second")
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errInvalidToken = errors.New("invalid or expired token")
+
+// signToken returns a tamper-proof token carrying fields until expiry. The
+// purpose is signed too, so a token made for one use can't be replayed for
+// another.
+func signToken(key []byte, purpose string, expiry time.Time, fields ...string) string{
+	parts := append([]string{purpose, strconv.FormatInt(expiry.Unix(), 10)}, fields...)
+	payload := []byte(strings.Join(parts, "\n"))
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyToken checks the signature, purpose and expiry of a token made by
+// signToken and returns its fields.
+func verifyToken(key []byte, purpose string, token string) ([]string, error){
+	encPayload, encSig, ok := strings.Cut(token, ".")
+	if !ok{
+		return nil, errInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil{
+		return nil, errInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil{
+		return nil, errInvalidToken
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)){
+		return nil, errInvalidToken
+	}
+
+	parts := strings.Split(string(payload), "\n")
+	if len(parts) < 2 || parts[0] != purpose{
+		return nil, errInvalidToken
+	}
+
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > expiry{
+		return nil, errInvalidToken
+	}
+
+	return parts[2:], nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+	"github.com/AVSanjay-12/snippetbox/internal/mailer"
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+)
+
+func TestVerifyToken(t *testing.T){
+	key := []byte("0123456789abcdef0123456789abcdef")
+	valid := signToken(key, "verify-email", time.Now().Add(time.Hour), "1", "alice@example.com")
+
+	tests := []struct{
+		name string
+		key []byte
+		purpose string
+		token string
+		wantErr bool
+	}{
+		{name: "Valid", key: key, purpose: "verify-email", token: valid},
+		{name: "Wrong key", key: []byte("another key"), purpose: "verify-email", token: valid, wantErr: true},
+		{name: "Wrong purpose", key: key, purpose: "reset-password", token: valid, wantErr: true},
+		{name: "Expired", key: key, purpose: "verify-email", token: signToken(key, "verify-email", time.Now().Add(-time.Second), "1"), wantErr: true},
+		{name: "Tampered", key: key, purpose: "verify-email", token: "x" + valid, wantErr: true},
+		{name: "Garbage", key: key, purpose: "verify-email", token: "garbage", wantErr: true},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			fields, err := verifyToken(tt.key, tt.purpose, tt.token)
+			assert.Equal(t, err != nil, tt.wantErr)
+			if !tt.wantErr{
+				assert.Equal(t, strings.Join(fields, ","), "1,alice@example.com")
+			}
+		})
+	}
+}
+
+type recordingSender struct{
+	sent []mailer.Message
+}
+
+func (s *recordingSender) Send(msg mailer.Message) error{
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func TestSendVerificationEmail(t *testing.T){
+	sender := &recordingSender{}
+	app := &application{
+		errorLog: log.New(io.Discard, "", 0),
+		infoLog: log.New(io.Discard, "", 0),
+		cfg: defaultConfig(),
+		secretKey: []byte("0123456789abcdef0123456789abcdef"),
+		mailer: mailer.New(sender),
+	}
+
+	app.sendVerificationEmail(&models.User{ID: 7, Name: "Alice", Email: "alice@example.com"})
+	app.wg.Wait()
+
+	assert.Equal(t, len(sender.sent), 1)
+	msg := sender.sent[0]
+	assert.Equal(t, msg.To, "alice@example.com")
+	assert.Equal(t, msg.Subject, "Confirm your Snippetbox email address")
+
+	link := regexp.MustCompile(`https://localhost:4000/user/verify\?token=\S+`).FindString(msg.Body)
+	u, err := url.Parse(link)
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	fields, err := verifyToken(app.secretKey, "verify-email", u.Query().Get("token"))
+	if err != nil{
+		t.Fatal(err)
+	}
+	assert.Equal(t, strings.Join(fields, ","), "7,alice@example.com")
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/AVSanjay-12/snippetbox/internal/validator"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+var errBinaryFile = errors.New("binary file")
+
+// decodeText returns an uploaded file as UTF-8. A byte order mark says
+// whether it is UTF-8 or UTF-16; without one, anything that isn't valid UTF-8
+// is taken to be Windows-1252, the usual legacy encoding. Files that look
+// binary are rejected.
+func decodeText(b []byte) (string, error){
+	if bytes.HasPrefix(b, []byte{0xFF, 0xFE}) || bytes.HasPrefix(b, []byte{0xFE, 0xFF}){
+		if len(b)%2 != 0{
+			return "", errBinaryFile
+		}
+		// The BOM decides the byte order and is dropped
+		decoded, err := unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder().Bytes(b)
+		if err != nil{
+			return "", errBinaryFile
+		}
+		b = decoded
+	}
+	b = bytes.TrimPrefix(b, []byte{0xEF, 0xBB, 0xBF})
+
+	if looksBinary(b){
+		return "", errBinaryFile
+	}
+	if utf8.Valid(b){
+		return string(b), nil
+	}
+
+	decoded, err := charmap.Windows1252.NewDecoder().Bytes(b)
+	if err != nil{
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+// looksBinary uses the same sniffing as browsers, and also catches NUL bytes
+// past the 512 bytes that looks at.
+func looksBinary(b []byte) bool{
+	if bytes.IndexByte(b, 0) >= 0{
+		return true
+	}
+	return !strings.HasPrefix(http.DetectContentType(b), "text/")
+}
+
+var uploadNameRX = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// uploadFileName makes an uploaded file's name into one a snippet file can
+// have, e.g. "my notes.txt" becomes "my-notes.txt".
+func uploadFileName(name string) string{
+	// Some browsers send the whole path, with either kind of slash
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Trim(uploadNameRX.ReplaceAllString(name, "-"), "-")
+	if len(name) > 100{
+		name = name[len(name)-100:]
+	}
+	if !validFileName(name){
+		return ""
+	}
+	return name
+}
+
+// readUploads adds the files uploaded with a multipart form to the form's
+// files. The title is taken from the first one if there isn't one.
+func (f *snippetFilesForm) readUploads(r *http.Request, v *validator.Validator) error{
+	if r.MultipartForm == nil{
+		return nil
+	}
+
+	for _, fh := range r.MultipartForm.File["upload"]{
+		// An empty file input still sends a part, with no name
+		if fh.Filename == "" && fh.Size == 0{
+			continue
+		}
+
+		file, err := fh.Open()
+		if err != nil{
+			return err
+		}
+		b, err := io.ReadAll(file)
+		file.Close()
+		if err != nil{
+			return err
+		}
+
+		content, err := decodeText(b)
+		if err != nil{
+			if errors.Is(err, errBinaryFile){
+				v.AddFieldErrors("upload", fmt.Sprintf("%s looks like a binary file. Only text files can be uploaded.", html.EscapeString(fh.Filename)))
+				continue
+			}
+			return err
+		}
+
+		if strings.TrimSpace(f.Title) == ""{
+			f.Title = strings.TrimSpace(path.Base(strings.ReplaceAll(fh.Filename, `\`, "/")))
+		}
+		f.Files = append(f.Files, snippetFileForm{Name: uploadFileName(fh.Filename), Content: content})
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+	"github.com/go-playground/form"
+)
+
+func TestDecodeText(t *testing.T){
+	tests := []struct{
+		name string
+		in []byte
+		want string
+		wantErr error
+	}{
+		{name: "UTF-8", in: []byte("naïve café\n"), want: "naïve café\n"},
+		{name: "UTF-8 BOM", in: []byte("\xEF\xBB\xBFhello"), want: "hello"},
+		{name: "UTF-16LE", in: []byte{0xFF, 0xFE, 'h', 0, 'i', 0, 0xE9, 0}, want: "hié"},
+		{name: "UTF-16BE", in: []byte{0xFE, 0xFF, 0, 'h', 0, 'i', 0, 0xE9}, want: "hié"},
+		{name: "Windows-1252", in: []byte("\x93quoted\x94 caf\xE9"), want: "\u201Cquoted\u201D café"},
+		{name: "Empty", in: []byte{}, want: ""},
+		{name: "NUL byte", in: []byte("text\x00more"), wantErr: errBinaryFile},
+		{name: "PNG", in: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), wantErr: errBinaryFile},
+		{name: "Odd length UTF-16", in: []byte{0xFF, 0xFE, 'h'}, wantErr: errBinaryFile},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			got, err := decodeText(tt.in)
+			assert.Equal(t, errors.Is(err, tt.wantErr), true)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
+
+func TestUploadFileName(t *testing.T){
+	tests := []struct{
+		name string
+		in string
+		want string
+	}{
+		{name: "Plain", in: "main.go", want: "main.go"},
+		{name: "Spaces", in: "my notes.txt", want: "my-notes.txt"},
+		{name: "Windows path", in: `C:\Users\me\my notes.txt`, want: "my-notes.txt"},
+		{name: "Unix path", in: "/var/log/app.log", want: "app.log"},
+		{name: "Accents", in: "café.md", want: "caf-.md"},
+		{name: "Nothing left", in: "???", want: ""},
+		{name: "Dots", in: "..", want: ""},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			assert.Equal(t, uploadFileName(tt.in), tt.want)
+		})
+	}
+}
+
+func TestReadUploads(t *testing.T){
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	mw.WriteField("files[0].content", "")
+	mw.WriteField("expires", "7")
+	for name, content := range map[string]string{"build log.txt": "ok\n", "logo.png": "\x89PNG\r\n\x1a\n\x00"}{
+		w, err := mw.CreateFormFile("upload", name)
+		if err != nil{
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	mw.Close()
+
+	r, err := http.NewRequest(http.MethodPost, "/snippet/create", body)
+	if err != nil{
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+
+	app := &application{formDecoder: form.NewDecoder()}
+
+	var f snippetCreateForm
+	err = app.decodePostForm(r, &f)
+	if err != nil{
+		t.Fatal(err)
+	}
+	assert.Equal(t, f.Expires, 7)
+
+	err = f.readUploads(r, &f.Validator)
+	if err != nil{
+		t.Fatal(err)
+	}
+	f.normalize()
+
+	assert.Equal(t, f.Title, "build log.txt")
+	assert.Equal(t, len(f.Files), 1)
+	assert.Equal(t, f.Files[0].Name, "build-log.txt")
+	assert.Equal(t, f.Files[0].Language, "text")
+	assert.Equal(t, f.Files[0].Content, "ok\n")
+	assert.Equal(t, f.FieldErrors["upload"], "logo.png looks like a binary file. Only text files can be uploaded.")
+}
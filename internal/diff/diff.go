@@ -0,0 +1,271 @@
+// Package diff compares texts line by line and groups the changes into
+// unified diff hunks, as diff -u does.
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// String is also the CSS class a line is shown with.
+func (op Op) String() string{
+	switch op{
+	case Delete:
+		return "delete"
+	case Insert:
+		return "insert"
+	}
+	return "equal"
+}
+
+type Line struct{
+	Op Op
+	Text string
+}
+
+// Prefix is the marker the line has in a unified diff.
+func (l Line) Prefix() string{
+	switch l.Op{
+	case Delete:
+		return "-"
+	case Insert:
+		return "+"
+	}
+	return " "
+}
+
+// Past this many changed lines the texts are treated as entirely different,
+// which keeps the work and memory bounded.
+const maxEdits = 1000
+
+// Lines returns the edits that turn a into b, with the fewest inserted and
+// deleted lines.
+func Lines(a, b []string) []Line{
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix]{
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix]{
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(a)+len(b)-prefix-suffix)
+	for _, s := range a[:prefix]{
+		lines = append(lines, Line{Equal, s})
+	}
+	lines = append(lines, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, s := range a[len(a)-suffix:]{
+		lines = append(lines, Line{Equal, s})
+	}
+	return lines
+}
+
+// myers is the algorithm from Eugene Myers' "An O(ND) Difference Algorithm
+// and Its Variations". trace[d] holds the furthest x reached on each diagonal
+// k after d edits, at index k+d.
+func myers(a, b []string) []Line{
+	n, m := len(a), len(b)
+
+	var trace [][]int
+	var prev []int
+	for d := 0; d <= n+m; d++{
+		if d > maxEdits{
+			return replace(a, b)
+		}
+
+		v := make([]int, 2*d+1)
+		for k := -d; k <= d; k += 2{
+			var x int
+			if d == 0{
+				x = 0
+			} else if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]){
+				x = prev[k+1+d-1]
+			} else{
+				x = prev[k-1+d-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y]{
+				x++
+				y++
+			}
+			v[k+d] = x
+
+			if x >= n && y >= m{
+				return backtrack(a, b, append(trace, v))
+			}
+		}
+		trace = append(trace, v)
+		prev = v
+	}
+	return replace(a, b)
+}
+
+func backtrack(a, b []string, trace [][]int) []Line{
+	x, y := len(a), len(b)
+	var lines []Line
+
+	for d := len(trace) - 1; d > 0; d--{
+		prev := trace[d-1]
+		k := x - y
+
+		var pk int
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]){
+			pk = k + 1
+		} else{
+			pk = k - 1
+		}
+		px := prev[pk+d-1]
+		py := px - pk
+
+		for x > px && y > py{
+			lines = append(lines, Line{Equal, a[x-1]})
+			x--
+			y--
+		}
+		if x == px{
+			lines = append(lines, Line{Insert, b[y-1]})
+			y--
+		} else{
+			lines = append(lines, Line{Delete, a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0{
+		lines = append(lines, Line{Equal, a[x-1]})
+		x--
+		y--
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1{
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines
+}
+
+func replace(a, b []string) []Line{
+	lines := make([]Line, 0, len(a)+len(b))
+	for _, s := range a{
+		lines = append(lines, Line{Delete, s})
+	}
+	for _, s := range b{
+		lines = append(lines, Line{Insert, s})
+	}
+	return lines
+}
+
+// Hunk is a run of changes with the unchanged lines around them. Starts are
+// 1-based line numbers.
+type Hunk struct{
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Lines []Line
+}
+
+// Header is the hunk's "@@ -1,4 +1,5 @@" line.
+func (h Hunk) Header() string{
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+func hunkRange(start, n int) string{
+	if n == 1{
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+// Unified compares two texts and returns the hunks of a unified diff, with
+// context unchanged lines around each change. Identical texts have none.
+func Unified(a, b string, context int) []Hunk{
+	lines := Lines(split(a), split(b))
+
+	// Line numbers in each text before lines[i]
+	oldPos := make([]int, len(lines)+1)
+	newPos := make([]int, len(lines)+1)
+	for i, l := range lines{
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if l.Op != Insert{
+			oldPos[i+1]++
+		}
+		if l.Op != Delete{
+			newPos[i+1]++
+		}
+	}
+
+	var hunks []Hunk
+	start, end := -1, -1
+	flush := func(){
+		if start < 0{
+			return
+		}
+		h := Hunk{
+			OldStart: oldPos[start] + 1,
+			OldLines: oldPos[end] - oldPos[start],
+			NewStart: newPos[start] + 1,
+			NewLines: newPos[end] - newPos[start],
+			Lines: lines[start:end],
+		}
+		// An empty range names the line before it, as diff -u does
+		if h.OldLines == 0{
+			h.OldStart--
+		}
+		if h.NewLines == 0{
+			h.NewStart--
+		}
+		hunks = append(hunks, h)
+	}
+
+	for i, l := range lines{
+		if l.Op == Equal{
+			continue
+		}
+		lo, hi := max(i-context, 0), min(i+context+1, len(lines))
+		if start >= 0 && lo <= end{
+			end = hi
+		} else{
+			flush()
+			start, end = lo, hi
+		}
+	}
+	flush()
+
+	return hunks
+}
+
+// Format writes hunks out as the text of a unified diff.
+func Format(oldName, newName string, hunks []Hunk) string{
+	if len(hunks) == 0{
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks{
+		sb.WriteString(h.Header() + "\n")
+		for _, l := range h.Lines{
+			sb.WriteString(l.Prefix() + l.Text + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// split breaks text into lines, ignoring a final newline and treating CRLF
+// the same as LF.
+func split(s string) []string{
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.TrimSuffix(s, "\n")
+	if s == ""{
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,76 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+)
+
+func TestLines(t *testing.T){
+	tests := []struct{
+		name string
+		a, b string
+		want string
+	}{
+		{name: "Same", a: "a b c", b: "a b c", want: " a  b  c"},
+		{name: "Empty to text", a: "", b: "a b", want: "+a +b"},
+		{name: "Text to empty", a: "a b", b: "", want: "-a -b"},
+		{name: "Changed line", a: "a b c", b: "a x c", want: " a -b +x  c"},
+		{name: "Inserted", a: "a c", b: "a b c", want: " a +b  c"},
+		{name: "Moved", a: "a b c d", b: "b c d a", want: "-a  b  c  d +a"},
+		{name: "Classic", a: "a b c a b b a", b: "c b a b a c", want: "-a -b  c +b  a  b -b  a +c"},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			lines := Lines(strings.Fields(tt.a), strings.Fields(tt.b))
+
+			var got []string
+			for _, l := range lines{
+				got = append(got, l.Prefix()+l.Text)
+			}
+			assert.Equal(t, strings.Join(got, " "), tt.want)
+		})
+	}
+}
+
+func TestLinesTooDifferent(t *testing.T){
+	var a, b []string
+	for i := 0; i < maxEdits; i++{
+		a = append(a, fmt.Sprint("a", i))
+		b = append(b, fmt.Sprint("b", i))
+	}
+
+	lines := Lines(a, b)
+	assert.Equal(t, len(lines), 2*maxEdits)
+	assert.Equal(t, lines[0].Op, Delete)
+	assert.Equal(t, lines[len(lines)-1].Op, Insert)
+}
+
+func TestUnified(t *testing.T){
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	b := "one\n2\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n"
+
+	want := `--- a
++++ b
+@@ -1,3 +1,3 @@
+ one
+-two
++2
+ three
+@@ -10 +10,2 @@
+ ten
++eleven
+`
+	assert.Equal(t, Format("a", "b", Unified(a, b, 1)), want)
+
+	// Close enough changes share a hunk
+	hunks := Unified(a, b, 4)
+	assert.Equal(t, len(hunks), 1)
+	assert.Equal(t, hunks[0].Header(), "@@ -1,10 +1,11 @@")
+
+	assert.Equal(t, Unified("", "new\n", 3)[0].Header(), "@@ -0,0 +1 @@")
+	assert.Equal(t, len(Unified("a\r\nb\r\n", "a\nb", 3)), 0)
+}
@@ -0,0 +1,153 @@
+// Package ldapauth checks logins against an LDAP directory by binding as the
+// user.
+package ldapauth
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+	"github.com/go-ldap/ldap/v3"
+)
+
+type Config struct{
+	// e.g. ldaps://ldap.example.com or ldap://ldap.example.com:389
+	URL string
+	// Upgrade an ldap:// connection with StartTLS
+	StartTLS bool
+	// Account used to look users up. Empty searches anonymously.
+	BindDN string
+	BindPassword string
+	// Where users are searched for, and the filter that finds one by the
+	// email they log in with, e.g. (mail=%s)
+	BaseDN string
+	UserFilter string
+	NameAttr string
+	EmailAttr string
+	// If set, only members of this group (by member or uniqueMember) may log in
+	RequiredGroup string
+	Timeout time.Duration
+}
+
+// Authenticator implements models.Authenticator. Users are found with a
+// search, then their password is checked by binding as them.
+type Authenticator struct{
+	Config
+
+	// Provision returns the local user for a directory user, creating one
+	// the first time they log in.
+	Provision func(name, email string) (int, error)
+}
+
+func (a *Authenticator) Authenticate(email, password string) (int, error){
+	// An empty password would make an unauthenticated bind, which most
+	// servers accept.
+	if email == "" || password == ""{
+		return 0, models.ErrInvalidCredentials
+	}
+
+	conn, err := a.dial()
+	if err != nil{
+		return 0, err
+	}
+	defer conn.Close()
+
+	if a.BindDN != ""{
+		err = conn.Bind(a.BindDN, a.BindPassword)
+		if err != nil{
+			return 0, fmt.Errorf("ldapauth: service bind: %w", err)
+		}
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(a.UserFilter, ldap.EscapeFilter(email)),
+		[]string{a.NameAttr, a.EmailAttr},
+		nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded){
+		return 0, fmt.Errorf("ldapauth: user search: %w", err)
+	}
+	// Nobody, or more than one entry - either way it isn't a login
+	if result == nil || len(result.Entries) != 1{
+		return 0, models.ErrInvalidCredentials
+	}
+	entry := result.Entries[0]
+
+	err = conn.Bind(entry.DN, password)
+	if err != nil{
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials){
+			return 0, models.ErrInvalidCredentials
+		}
+		return 0, fmt.Errorf("ldapauth: user bind: %w", err)
+	}
+
+	if a.RequiredGroup != ""{
+		member, err := a.isMember(conn, entry.DN)
+		if err != nil{
+			return 0, err
+		}
+		if !member{
+			return 0, models.ErrInvalidCredentials
+		}
+	}
+
+	name := entry.GetAttributeValue(a.NameAttr)
+	mail := entry.GetAttributeValue(a.EmailAttr)
+	if mail == ""{
+		mail = email
+	}
+	if name == ""{
+		name, _, _ = strings.Cut(mail, "@")
+	}
+
+	return a.Provision(name, mail)
+}
+
+func (a *Authenticator) dial() (*ldap.Conn, error){
+	u, err := url.Parse(a.URL)
+	if err != nil{
+		return nil, err
+	}
+
+	conn, err := ldap.DialURL(a.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: a.Timeout}),
+		ldap.DialWithTLSConfig(&tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}))
+	if err != nil{
+		return nil, fmt.Errorf("ldapauth: %w", err)
+	}
+	conn.SetTimeout(a.Timeout)
+
+	if a.StartTLS{
+		err = conn.StartTLS(&tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12})
+		if err != nil{
+			conn.Close()
+			return nil, fmt.Errorf("ldapauth: starttls: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// isMember reads the group entry, bound as the user, and checks it lists them.
+func (a *Authenticator) isMember(conn *ldap.Conn, userDN string) (bool, error){
+	dn := ldap.EscapeFilter(userDN)
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.RequiredGroup, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 0, false,
+		fmt.Sprintf("(|(member=%s)(uniqueMember=%s))", dn, dn),
+		[]string{"dn"},
+		nil,
+	))
+	if err != nil{
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject){
+			return false, errors.New("ldapauth: required group " + a.RequiredGroup + " does not exist")
+		}
+		return false, fmt.Errorf("ldapauth: group search: %w", err)
+	}
+	return len(result.Entries) > 0, nil
+}
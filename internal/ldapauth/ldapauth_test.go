@@ -0,0 +1,271 @@
+package ldapauth
+
+import (
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+	"github.com/AVSanjay-12/snippetbox/internal/models"
+	"github.com/jimlambrt/gldap"
+)
+
+// testEntry is an entry in the test directory. Entries with a password can be
+// bound as.
+type testEntry struct{
+	dn string
+	password string
+	attrs map[string][]string
+}
+
+var testEntries = []testEntry{
+	{
+		dn: "cn=snippetbox,ou=services,dc=example,dc=com",
+		password: "service-secret",
+	},
+	{
+		dn: "uid=alice,ou=people,dc=example,dc=com",
+		password: "alice-secret",
+		attrs: map[string][]string{"cn": {"Alice Jones"}, "mail": {"alice@example.com"}},
+	},
+	{
+		dn: "uid=bob,ou=people,dc=example,dc=com",
+		password: "bob-secret",
+		attrs: map[string][]string{"cn": {"Bob Smith"}, "mail": {"bob@example.com"}},
+	},
+	{
+		dn: "cn=snippetbox-users,ou=groups,dc=example,dc=com",
+		attrs: map[string][]string{"member": {"uid=alice,ou=people,dc=example,dc=com"}},
+	},
+}
+
+// Only the filters the authenticator sends are understood: a single
+// (attr=value), or an OR of them.
+var filterTermRX = regexp.MustCompile(`\(([A-Za-z]+)=([^()]*)\)`)
+
+func matchesFilter(e testEntry, filter string) bool{
+	for _, term := range filterTermRX.FindAllStringSubmatch(filter, -1){
+		for attr, values := range e.attrs{
+			if !strings.EqualFold(attr, term[1]){
+				continue
+			}
+			for _, v := range values{
+				if strings.EqualFold(v, term[2]){
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// startDirectory runs an LDAP server on a free local port for the test and
+// returns its URL.
+func startDirectory(t *testing.T) string{
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil{
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	mux, err := gldap.NewMux()
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	mux.Bind(func(w *gldap.ResponseWriter, r *gldap.Request){
+		resp := r.NewBindResponse(gldap.WithResponseCode(gldap.ResultInvalidCredentials))
+		defer w.Write(resp)
+
+		m, err := r.GetSimpleBindMessage()
+		if err != nil{
+			return
+		}
+		for _, e := range testEntries{
+			if e.password != "" && e.dn == m.UserName && e.password == string(m.Password){
+				resp.SetResultCode(gldap.ResultSuccess)
+			}
+		}
+	})
+
+	mux.Search(func(w *gldap.ResponseWriter, r *gldap.Request){
+		done := r.NewSearchDoneResponse(gldap.WithResponseCode(gldap.ResultSuccess))
+		defer w.Write(done)
+
+		m, err := r.GetSearchMessage()
+		if err != nil{
+			done.SetResultCode(gldap.ResultOperationsError)
+			return
+		}
+
+		found := false
+		for _, e := range testEntries{
+			inScope := strings.HasSuffix(e.dn, ","+m.BaseDN)
+			if m.Scope == gldap.BaseObject{
+				inScope = e.dn == m.BaseDN
+			}
+			if e.dn == m.BaseDN{
+				found = true
+			}
+			if inScope && matchesFilter(e, m.Filter){
+				w.Write(r.NewSearchResponseEntry(e.dn, gldap.WithAttributes(e.attrs)))
+			}
+		}
+		if m.Scope == gldap.BaseObject && !found{
+			done.SetResultCode(gldap.ResultNoSuchObject)
+		}
+	})
+
+	s, err := gldap.NewServer()
+	if err != nil{
+		t.Fatal(err)
+	}
+	s.Router(mux)
+
+	go s.Run(addr)
+	t.Cleanup(func(){ s.Stop() })
+
+	for !s.Ready(){
+		time.Sleep(time.Millisecond)
+	}
+	return "ldap://" + addr
+}
+
+func TestAuthenticate(t *testing.T){
+	url := startDirectory(t)
+
+	var provisioned []string
+	newAuthenticator := func(group string) *Authenticator{
+		return &Authenticator{
+			Config: Config{
+				URL: url,
+				BindDN: "cn=snippetbox,ou=services,dc=example,dc=com",
+				BindPassword: "service-secret",
+				BaseDN: "ou=people,dc=example,dc=com",
+				UserFilter: "(mail=%s)",
+				NameAttr: "cn",
+				EmailAttr: "mail",
+				RequiredGroup: group,
+				Timeout: time.Second,
+			},
+			Provision: func(name, email string) (int, error){
+				provisioned = append(provisioned, name+" <"+email+">")
+				return 7, nil
+			},
+		}
+	}
+
+	tests := []struct{
+		name string
+		group string
+		email string
+		password string
+		wantID int
+		wantErr error
+		wantProvisioned string
+	}{
+		{
+			name: "Valid",
+			email: "alice@example.com",
+			password: "alice-secret",
+			wantID: 7,
+			wantProvisioned: "Alice Jones <alice@example.com>",
+		},
+		{
+			name: "Email in a different case",
+			email: "BOB@example.com",
+			password: "bob-secret",
+			wantID: 7,
+			wantProvisioned: "Bob Smith <bob@example.com>",
+		},
+		{
+			name: "Wrong password",
+			email: "alice@example.com",
+			password: "wrong",
+			wantErr: models.ErrInvalidCredentials,
+		},
+		{
+			name: "Empty password",
+			email: "alice@example.com",
+			password: "",
+			wantErr: models.ErrInvalidCredentials,
+		},
+		{
+			name: "Unknown user",
+			email: "carol@example.com",
+			password: "alice-secret",
+			wantErr: models.ErrInvalidCredentials,
+		},
+		{
+			name: "Filter injection",
+			email: "*)(mail=alice@example.com",
+			password: "alice-secret",
+			wantErr: models.ErrInvalidCredentials,
+		},
+		{
+			name: "Member of required group",
+			group: "cn=snippetbox-users,ou=groups,dc=example,dc=com",
+			email: "alice@example.com",
+			password: "alice-secret",
+			wantID: 7,
+			wantProvisioned: "Alice Jones <alice@example.com>",
+		},
+		{
+			name: "Not in required group",
+			group: "cn=snippetbox-users,ou=groups,dc=example,dc=com",
+			email: "bob@example.com",
+			password: "bob-secret",
+			wantErr: models.ErrInvalidCredentials,
+		},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			provisioned = nil
+
+			id, err := newAuthenticator(tt.group).Authenticate(tt.email, tt.password)
+			assert.Equal(t, errors.Is(err, tt.wantErr), true)
+			assert.Equal(t, id, tt.wantID)
+			assert.Equal(t, strings.Join(provisioned, ","), tt.wantProvisioned)
+		})
+	}
+}
+
+func TestAuthenticateMissingGroup(t *testing.T){
+	a := &Authenticator{
+		Config: Config{
+			URL: startDirectory(t),
+			BaseDN: "ou=people,dc=example,dc=com",
+			UserFilter: "(mail=%s)",
+			NameAttr: "cn",
+			EmailAttr: "mail",
+			RequiredGroup: "cn=no-such-group,ou=groups,dc=example,dc=com",
+			Timeout: time.Second,
+		},
+		Provision: func(name, email string) (int, error){ return 7, nil },
+	}
+
+	// A misconfigured group is an error, not a failed login
+	_, err := a.Authenticate("alice@example.com", "alice-secret")
+	assert.Equal(t, err != nil, true)
+	assert.Equal(t, errors.Is(err, models.ErrInvalidCredentials), false)
+}
+
+func TestAuthenticateUnreachable(t *testing.T){
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil{
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	a := &Authenticator{Config: Config{URL: "ldap://" + addr, Timeout: time.Second}}
+
+	_, err = a.Authenticate("alice@example.com", "alice-secret")
+	assert.Equal(t, err != nil, true)
+	assert.Equal(t, errors.Is(err, models.ErrInvalidCredentials), false)
+}
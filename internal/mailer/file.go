@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// FileSender is for local development and tests. Each message is written to
+// Dir as an .eml file, or to Log when Dir is empty.
+type FileSender struct{
+	Dir string
+	Log *log.Logger
+	From string
+	// Strip the query strings from links written to Log. They carry the
+	// tokens in verification and password reset links, which anyone who can
+	// read the log could otherwise use.
+	Redact bool
+
+	mu sync.Mutex
+	count int
+}
+
+var linkQueryRX = regexp.MustCompile(`(https?://[^\s?#]+)\?\S*`)
+
+func (s *FileSender) Send(msg Message) error{
+	if s.Dir == ""{
+		if s.Redact{
+			msg.Body = linkQueryRX.ReplaceAllString(msg.Body, "$1?[redacted]")
+		}
+		s.Log.Printf("mail to %s:\n%s", msg.To, format(s.From, msg))
+		return nil
+	}
+
+	raw := format(s.From, msg)
+
+	s.mu.Lock()
+	s.count++
+	name := fmt.Sprintf("%s-%04d.eml", time.Now().UTC().Format("20060102T150405"), s.count)
+	s.mu.Unlock()
+
+	err := os.MkdirAll(s.Dir, 0700)
+	if err != nil{
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.Dir, name), raw, 0600)
+}
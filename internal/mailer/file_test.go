@@ -0,0 +1,65 @@
+package mailer
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+)
+
+func TestFileSender(t *testing.T){
+	msg := Message{
+		To: "alice@example.com",
+		Subject: "Reset your password",
+		Body: "Open https://snippetbox.example/user/password/reset?token=s3cret to reset it.\n",
+	}
+
+	tests := []struct{
+		name string
+		dir bool
+		redact bool
+		wantToken bool
+	}{
+		{name: "Log", wantToken: true},
+		{name: "Log, redacted", redact: true},
+		{name: "Directory", dir: true, redact: true, wantToken: true},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			logged := new(bytes.Buffer)
+			s := &FileSender{Log: log.New(logged, "", 0), From: "no-reply@snippetbox.example", Redact: tt.redact}
+			if tt.dir{
+				s.Dir = t.TempDir()
+			}
+
+			err := s.Send(msg)
+			if err != nil{
+				t.Fatal(err)
+			}
+
+			out := logged.String()
+			if tt.dir{
+				files, err := filepath.Glob(filepath.Join(s.Dir, "*.eml"))
+				if err != nil{
+					t.Fatal(err)
+				}
+				assert.Equal(t, len(files), 1)
+
+				b, err := os.ReadFile(files[0])
+				if err != nil{
+					t.Fatal(err)
+				}
+				out = string(b)
+			}
+
+			assert.Equal(t, strings.Contains(out, "To: alice@example.com"), true)
+			assert.Equal(t, strings.Contains(out, "token=s3cret"), tt.wantToken)
+			assert.Equal(t, strings.Contains(out, "https://snippetbox.example/user/password/reset?[redacted] to reset it"), !tt.wantToken)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"text/template"
+)
+
+//go:embed "templates"
+var templateFS embed.FS
+
+type Message struct{
+	To string
+	Subject string
+	Body string
+}
+
+// Sender delivers a rendered message - over SMTP, or to a file or log for
+// local development and tests.
+type Sender interface{
+	Send(msg Message) error
+}
+
+// Mailer renders the embedded email templates and hands them to a Sender.
+type Mailer struct{
+	sender Sender
+}
+
+func New(sender Sender) *Mailer{
+	return &Mailer{sender: sender}
+}
+
+// Send renders templateFile, which must define "subject" and "plainBody",
+// with data and sends it to recipient.
+func (m *Mailer) Send(recipient, templateFile string, data any) error{
+	tmpl, err := template.New("email").ParseFS(templateFS, "templates/"+templateFile)
+	if err != nil{
+		return err
+	}
+
+	subject := new(bytes.Buffer)
+	err = tmpl.ExecuteTemplate(subject, "subject", data)
+	if err != nil{
+		return err
+	}
+
+	body := new(bytes.Buffer)
+	err = tmpl.ExecuteTemplate(body, "plainBody", data)
+	if err != nil{
+		return err
+	}
+
+	return m.sender.Send(Message{
+		To: recipient,
+		Subject: subject.String(),
+		Body: body.String(),
+	})
+}
@@ -0,0 +1,101 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// Connection and delivery deadline for a single message
+const smtpTimeout = 10 * time.Second
+
+type SMTPSender struct{
+	Host string
+	Port int
+	Username string
+	Password string
+	// From is the sender, e.g. "Snippetbox <no-reply@example.com>"
+	From string
+}
+
+func (s *SMTPSender) Send(msg Message) error{
+	from, err := mail.ParseAddress(s.From)
+	if err != nil{
+		return err
+	}
+
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	conn, err := net.DialTimeout("tcp", addr, smtpTimeout)
+	if err != nil{
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil{
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok{
+		err = c.StartTLS(&tls.Config{ServerName: s.Host})
+		if err != nil{
+			return err
+		}
+	}
+
+	// net/smtp refuses PLAIN auth over an unencrypted connection to anything
+	// but localhost.
+	if s.Username != ""{
+		err = c.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host))
+		if err != nil{
+			return err
+		}
+	}
+
+	err = c.Mail(from.Address)
+	if err != nil{
+		return err
+	}
+	err = c.Rcpt(msg.To)
+	if err != nil{
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil{
+		return err
+	}
+	_, err = w.Write(format(s.From, msg))
+	if err != nil{
+		return err
+	}
+	err = w.Close()
+	if err != nil{
+		return err
+	}
+
+	return c.Quit()
+}
+
+// format builds an RFC 5322 message with a plain text UTF-8 body.
+func format(from string, msg Message) []byte{
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "From: %s\r\n", from)
+	fmt.Fprintf(buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(msg.Body)
+	return buf.Bytes()
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Audited actions
+const (
+	AuditSignup = "user.signup"
+	AuditLogin = "user.login"
+	AuditLoginFailed = "user.login_failed"
+	AuditLogout = "user.logout"
+	AuditPasswordChange = "user.password_change"
+	AuditPasswordReset = "user.password_reset"
+	AuditSessionRevoke = "user.session_revoke"
+	AuditRememberTokenReused = "user.remember_token_reused"
+	AuditAccountDelete = "user.delete"
+	AuditAccountExport = "user.export"
+	AuditSnippetCreate = "snippet.create"
+	AuditSnippetEdit = "snippet.edit"
+	AuditSnippetDelete = "snippet.delete"
+	AuditAdminUserRole = "admin.user.role"
+	AuditAdminUserDisable = "admin.user.disable"
+	AuditAdminUserEnable = "admin.user.enable"
+	AuditAdminSnippetExpire = "admin.snippet.expire"
+	AuditAdminSnippetDelete = "admin.snippet.delete"
+)
+
+// AuditEntry records who did what, and from where. Detail holds whatever
+// else is worth knowing about the action, such as the record it affected.
+type AuditEntry struct{
+	ID int64					`json:"id"`
+	Created time.Time			`json:"created"`
+	// 0 when nobody was signed in
+	ActorID int					`json:"actor_id"`
+	// Filled in when entries are read, if the actor still exists
+	ActorEmail string			`json:"actor_email,omitempty"`
+	Action string				`json:"action"`
+	IP string					`json:"ip"`
+	UserAgent string			`json:"user_agent"`
+	RequestID string			`json:"request_id"`
+	Detail map[string]any		`json:"detail"`
+}
+
+// DetailJSON is the detail as it is stored, for display.
+func (e *AuditEntry) DetailJSON() string{
+	b, _ := json.Marshal(e.Detail)
+	return string(b)
+}
+
+// AuditFilter narrows a query. Zero fields match everything.
+type AuditFilter struct{
+	ActorID int
+	Since time.Time
+	Until time.Time
+}
+
+// AuditModel is append-only - entries are never changed once written, and
+// only removed by Prune.
+type AuditModel struct{
+	DB *sql.DB
+}
+
+func (m *AuditModel) Insert(e *AuditEntry) error{
+	if e.Detail == nil{
+		e.Detail = map[string]any{}
+	}
+	detail, err := json.Marshal(e.Detail)
+	if err != nil{
+		return err
+	}
+
+	stmt := `INSERT INTO audit_log (created, actor_id, action, ip, user_agent, request_id, detail)
+	VALUES(UTC_TIMESTAMP(), NULLIF(?, 0), ?, ?, ?, ?, ?)`
+
+	_, err = m.DB.Exec(stmt, e.ActorID, e.Action, e.IP, truncate(e.UserAgent, 255), truncate(e.RequestID, 64), detail)
+	return err
+}
+
+func (f AuditFilter) where() (string, []any){
+	var conds []string
+	var args []any
+
+	if f.ActorID != 0{
+		conds = append(conds, "a.actor_id = ?")
+		args = append(args, f.ActorID)
+	}
+	if !f.Since.IsZero(){
+		conds = append(conds, "a.created >= ?")
+		args = append(args, f.Since.UTC())
+	}
+	if !f.Until.IsZero(){
+		conds = append(conds, "a.created < ?")
+		args = append(args, f.Until.UTC())
+	}
+
+	if len(conds) == 0{
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// Search returns a page of matching entries, newest first, and how many match
+// in total.
+func (m *AuditModel) Search(f AuditFilter, limit, offset int) ([]*AuditEntry, int, error){
+	where, args := f.where()
+
+	var total int
+	err := m.DB.QueryRow(`SELECT COUNT(*) FROM audit_log a`+where, args...).Scan(&total)
+	if err != nil{
+		return nil, 0, err
+	}
+
+	entries := []*AuditEntry{}
+	err = m.each(where+` ORDER BY a.id DESC LIMIT ? OFFSET ?`, append(args, limit, offset), func(e *AuditEntry) error{
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil{
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+// Each calls fn for every matching entry, oldest first, without holding them
+// all in memory - exports can be large.
+func (m *AuditModel) Each(f AuditFilter, fn func(*AuditEntry) error) error{
+	where, args := f.where()
+	return m.each(where+` ORDER BY a.id`, args, fn)
+}
+
+func (m *AuditModel) each(clauses string, args []any, fn func(*AuditEntry) error) error{
+	stmt := `SELECT a.id, a.created, COALESCE(a.actor_id, 0), COALESCE(u.email, ''), a.action, a.ip,
+	a.user_agent, a.request_id, a.detail
+	FROM audit_log a LEFT JOIN users u ON u.id = a.actor_id` + clauses
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil{
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next(){
+		e := &AuditEntry{}
+		var detail []byte
+
+		err = rows.Scan(&e.ID, &e.Created, &e.ActorID, &e.ActorEmail, &e.Action, &e.IP, &e.UserAgent, &e.RequestID, &detail)
+		if err != nil{
+			return err
+		}
+		err = json.Unmarshal(detail, &e.Detail)
+		if err != nil{
+			return err
+		}
+
+		err = fn(e)
+		if err != nil{
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Prune deletes entries older than before, returning how many went.
+func (m *AuditModel) Prune(before time.Time) (int64, error){
+	stmt := `DELETE FROM audit_log WHERE created < ?`
+
+	result, err := m.DB.Exec(stmt, before.UTC())
+	if err != nil{
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string{
+	if len(s) <= n{
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]){
+		n--
+	}
+	return s[:n]
+}
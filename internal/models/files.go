@@ -0,0 +1,79 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// SnippetFile is one named file in a snippet.
+type SnippetFile struct{
+	Name string
+	// Empty for plain text
+	Language string
+	Content string
+}
+
+// querier is what's shared by *sql.DB and *sql.Tx for reading.
+type querier interface{
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// snippetFiles returns a snippet's current files in order.
+func snippetFiles(db querier, snippetID int) ([]SnippetFile, error){
+	stmt := `SELECT name, language, content FROM snippet_files
+	WHERE snippet_id = ? ORDER BY position`
+
+	return scanFiles(db, stmt, snippetID)
+}
+
+func scanFiles(db querier, stmt string, args ...any) ([]SnippetFile, error){
+	rows, err := db.Query(stmt, args...)
+	if err != nil{
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := []SnippetFile{}
+
+	for rows.Next(){
+		var f SnippetFile
+
+		err = rows.Scan(&f.Name, &f.Language, &f.Content)
+		if err != nil{
+			return nil, err
+		}
+
+		files = append(files, f)
+	}
+
+	if err = rows.Err(); err != nil{
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// insertFiles stores files as a snippet's current ones, in order.
+func insertFiles(tx *sql.Tx, snippetID int, files []SnippetFile) error{
+	stmt := `INSERT INTO snippet_files (snippet_id, position, name, language, content) VALUES (?, ?, ?, ?, ?)`
+
+	for i, f := range files{
+		_, err := tx.Exec(stmt, snippetID, i, f.Name, f.Language, f.Content)
+		if err != nil{
+			return err
+		}
+	}
+	return nil
+}
+
+// sameFiles reports whether a and b are the same files in the same order.
+func sameFiles(a, b []SnippetFile) bool{
+	if len(a) != len(b){
+		return false
+	}
+	for i := range a{
+		if a[i] != b[i]{
+			return false
+		}
+	}
+	return true
+}
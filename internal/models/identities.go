@@ -0,0 +1,43 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// IdentityModel maps an account at an external identity provider, named by
+// its issuer and subject, to a local user.
+type IdentityModel struct{
+	DB *sql.DB
+}
+
+func (m *IdentityModel) UserID(issuer, subject string) (int, error){
+	var userID int
+
+	stmt := `SELECT user_id FROM user_identities WHERE issuer = ? AND subject = ?`
+
+	err := m.DB.QueryRow(stmt, issuer, subject).Scan(&userID)
+	if err != nil{
+		if errors.Is(err, sql.ErrNoRows){
+			return 0, ErrNoRecord
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+func (m *IdentityModel) Link(issuer, subject string, userID int) error{
+	stmt := `INSERT INTO user_identities (issuer, subject, user_id, created)
+	VALUES(?, ?, ?, UTC_TIMESTAMP())`
+
+	_, err := m.DB.Exec(stmt, issuer, subject, userID)
+	return err
+}
+
+// Linked reports whether a user has signed in through an identity provider.
+func (m *IdentityModel) Linked(userID int) (bool, error){
+	var linked bool
+	stmt := "SELECT EXISTS(SELECT true FROM user_identities WHERE user_id = ?)"
+	err := m.DB.QueryRow(stmt, userID).Scan(&linked)
+	return linked, err
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+type LoginAttempts struct{
+	Failures int
+	LastFailure time.Time
+}
+
+// LoginAttemptStore keeps failed login counts, keyed by account or client IP.
+// Failures older than the window passed to RecordFailure no longer count.
+type LoginAttemptStore interface{
+	Get(key string) (LoginAttempts, error)
+	RecordFailure(key string, window time.Duration) (LoginAttempts, error)
+	Reset(key string) error
+}
+
+// Database-backed store, shared by every instance of the app
+type LoginAttemptModel struct{
+	DB *sql.DB
+}
+
+func (m *LoginAttemptModel) Get(key string) (LoginAttempts, error){
+	var a LoginAttempts
+
+	stmt := `SELECT failures, last_failure FROM login_attempts WHERE attempt_key = ?`
+
+	err := m.DB.QueryRow(stmt, key).Scan(&a.Failures, &a.LastFailure)
+	if err != nil && !errors.Is(err, sql.ErrNoRows){
+		return LoginAttempts{}, err
+	}
+	return a, nil
+}
+
+func (m *LoginAttemptModel) RecordFailure(key string, window time.Duration) (LoginAttempts, error){
+	// MySQL applies the assignments in order, so failures is computed against
+	// the previous last_failure.
+	stmt := `INSERT INTO login_attempts (attempt_key, failures, last_failure)
+	VALUES(?, 1, UTC_TIMESTAMP())
+	ON DUPLICATE KEY UPDATE
+		failures = IF(last_failure < DATE_SUB(UTC_TIMESTAMP(), INTERVAL ? SECOND), 1, failures + 1),
+		last_failure = UTC_TIMESTAMP()`
+
+	_, err := m.DB.Exec(stmt, key, int(window.Seconds()))
+	if err != nil{
+		return LoginAttempts{}, err
+	}
+	return m.Get(key)
+}
+
+func (m *LoginAttemptModel) Reset(key string) error{
+	_, err := m.DB.Exec(`DELETE FROM login_attempts WHERE attempt_key = ?`, key)
+	return err
+}
+
+// In-memory store for a single instance. Counts are lost on restart.
+type MemoryLoginAttempts struct{
+	mu sync.Mutex
+	attempts map[string]LoginAttempts
+	lastSweep time.Time
+}
+
+func NewMemoryLoginAttempts() *MemoryLoginAttempts{
+	return &MemoryLoginAttempts{attempts: map[string]LoginAttempts{}}
+}
+
+func (m *MemoryLoginAttempts) Get(key string) (LoginAttempts, error){
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.attempts[key], nil
+}
+
+func (m *MemoryLoginAttempts) RecordFailure(key string, window time.Duration) (LoginAttempts, error){
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+
+	// Drop expired entries now and then so the map doesn't grow forever
+	if now.Sub(m.lastSweep) > window{
+		for k, a := range m.attempts{
+			if now.Sub(a.LastFailure) > window{
+				delete(m.attempts, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	a := m.attempts[key]
+	if now.Sub(a.LastFailure) > window{
+		a.Failures = 0
+	}
+	a.Failures++
+	a.LastFailure = now
+
+	m.attempts[key] = a
+	return a, nil
+}
+
+func (m *MemoryLoginAttempts) Reset(key string) error{
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+	return nil
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"time"
+)
+
+type PasswordResetModel struct{
+	DB *sql.DB
+}
+
+// newToken returns a random token for the user to hold and the SHA-256 hash
+// that is stored in its place.
+func newToken() (string, []byte, error){
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil{
+		return "", nil, err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	hash := sha256.Sum256([]byte(token))
+	return token, hash[:], nil
+}
+
+func hashToken(token string) []byte{
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
+}
+
+// New creates a reset token for the user, valid for ttl.
+func (m *PasswordResetModel) New(userID int, ttl time.Duration) (string, error){
+	token, hash, err := newToken()
+	if err != nil{
+		return "", err
+	}
+
+	stmt := `INSERT INTO password_resets (hash, user_id, expiry)
+	VALUES(?, ?, DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? SECOND))`
+
+	_, err = m.DB.Exec(stmt, hash, userID, int(ttl.Seconds()))
+	if err != nil{
+		return "", err
+	}
+	return token, nil
+}
+
+// UserID returns the user an unexpired token belongs to.
+func (m *PasswordResetModel) UserID(token string) (int, error){
+	var userID int
+
+	stmt := `SELECT user_id FROM password_resets WHERE hash = ? AND expiry > UTC_TIMESTAMP()`
+
+	err := m.DB.QueryRow(stmt, hashToken(token)).Scan(&userID)
+	if err != nil{
+		if errors.Is(err, sql.ErrNoRows){
+			return 0, ErrNoRecord
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+// Consume uses up an unexpired token and returns the user it belongs to. The
+// token is deleted first, so of two requests racing with the same token only
+// one gets a user back; the other gets ErrNoRecord.
+func (m *PasswordResetModel) Consume(token string) (int, error){
+	userID, err := m.UserID(token)
+	if err != nil{
+		return 0, err
+	}
+
+	stmt := `DELETE FROM password_resets WHERE hash = ? AND expiry > UTC_TIMESTAMP()`
+
+	result, err := m.DB.Exec(stmt, hashToken(token))
+	if err != nil{
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil{
+		return 0, err
+	}
+	if n == 0{
+		return 0, ErrNoRecord
+	}
+
+	return userID, m.DeleteAllForUser(userID)
+}
+
+// DeleteAllForUser is called once a reset is used, which makes every token
+// the user was sent single-use.
+func (m *PasswordResetModel) DeleteAllForUser(userID int) error{
+	_, err := m.DB.Exec(`DELETE FROM password_resets WHERE user_id = ?`, userID)
+	return err
+}
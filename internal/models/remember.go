@@ -0,0 +1,131 @@
+package models
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrTokenReused means a remember-me token was presented after it had been
+// replaced - someone else has a copy of it.
+var ErrTokenReused = errors.New("models: remember-me token reused")
+
+// A replaced token is still accepted this soon afterwards, for requests that
+// were already on their way with it
+const rememberGrace = 30 * time.Second
+
+// RememberTokenModel stores the hashes of remember-me tokens, one for each
+// session that has one.
+type RememberTokenModel struct{
+	DB *sql.DB
+}
+
+// New issues a token for the session that lasts for ttl, whatever it is
+// rotated to in the meantime.
+func (m *RememberTokenModel) New(sessionID string, userID int, ttl time.Duration) (string, error){
+	token, hash, err := newToken()
+	if err != nil{
+		return "", err
+	}
+
+	stmt := `INSERT INTO remember_tokens (session_id, user_id, hash, rotated, expiry)
+	VALUES(?, ?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? SECOND))`
+
+	_, err = m.DB.Exec(stmt, sessionID, userID, hash, int(ttl.Seconds()))
+	if err != nil{
+		return "", err
+	}
+	return token, nil
+}
+
+// Rotate checks the session's token and replaces it, returning the user it
+// belongs to and the new token. The new token is empty when the one given was
+// replaced moments ago, by a request racing this one.
+//
+// A token replaced longer ago than that is ErrTokenReused, along with the
+// user, and the session's token is deleted. Any other token is ErrNoRecord.
+func (m *RememberTokenModel) Rotate(sessionID, token string) (int, string, error){
+	tx, err := m.DB.Begin()
+	if err != nil{
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	var hash, previousHash []byte
+	var rotated time.Time
+
+	stmt := `SELECT user_id, hash, previous_hash, rotated FROM remember_tokens
+	WHERE session_id = ? AND expiry > UTC_TIMESTAMP() FOR UPDATE`
+
+	err = tx.QueryRow(stmt, sessionID).Scan(&userID, &hash, &previousHash, &rotated)
+	if err != nil{
+		if errors.Is(err, sql.ErrNoRows){
+			return 0, "", ErrNoRecord
+		}
+		return 0, "", err
+	}
+
+	switch checkRememberToken(hashToken(token), hash, previousHash, rotated){
+	case rememberCurrent:
+		newToken, newHash, err := newToken()
+		if err != nil{
+			return 0, "", err
+		}
+
+		stmt = `UPDATE remember_tokens SET hash = ?, previous_hash = ?, rotated = UTC_TIMESTAMP()
+		WHERE session_id = ?`
+
+		_, err = tx.Exec(stmt, newHash, hash, sessionID)
+		if err != nil{
+			return 0, "", err
+		}
+		return userID, newToken, tx.Commit()
+
+	case rememberRacing:
+		return userID, "", nil
+
+	case rememberReused:
+		_, err = tx.Exec(`DELETE FROM remember_tokens WHERE session_id = ?`, sessionID)
+		if err != nil{
+			return 0, "", err
+		}
+		err = tx.Commit()
+		if err != nil{
+			return 0, "", err
+		}
+		return userID, "", ErrTokenReused
+	}
+
+	// Not a token this session ever had, so nothing says it was stolen
+	return 0, "", ErrNoRecord
+}
+
+type rememberCheck int
+
+const (
+	// The session's current token
+	rememberCurrent rememberCheck = iota
+	// The token it replaced, within the grace period
+	rememberRacing
+	// The token it replaced, after the grace period
+	rememberReused
+	// Neither
+	rememberUnknown
+)
+
+// checkRememberToken compares the hash of a presented token with a session's
+// current and previous hashes.
+func checkRememberToken(given, hash, previousHash []byte, rotated time.Time) rememberCheck{
+	switch{
+	case subtle.ConstantTimeCompare(given, hash) == 1:
+		return rememberCurrent
+	case subtle.ConstantTimeCompare(given, previousHash) == 1:
+		if time.Since(rotated) < rememberGrace{
+			return rememberRacing
+		}
+		return rememberReused
+	}
+	return rememberUnknown
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+)
+
+func TestCheckRememberToken(t *testing.T){
+	current := hashToken("current")
+	previous := hashToken("previous")
+
+	tests := []struct{
+		name string
+		token string
+		previousHash []byte
+		rotated time.Time
+		want rememberCheck
+	}{
+		{name: "Current", token: "current", previousHash: previous, rotated: time.Now(), want: rememberCurrent},
+		{name: "Previous within grace", token: "previous", previousHash: previous, rotated: time.Now().Add(-5 * time.Second), want: rememberRacing},
+		{name: "Previous after grace", token: "previous", previousHash: previous, rotated: time.Now().Add(-time.Hour), want: rememberReused},
+		{name: "Unknown", token: "forged", previousHash: previous, rotated: time.Now().Add(-time.Hour), want: rememberUnknown},
+		{name: "Unknown, never rotated", token: "forged", rotated: time.Now().Add(-time.Hour), want: rememberUnknown},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			got := checkRememberToken(hashToken(tt.token), current, tt.previousHash, tt.rotated)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
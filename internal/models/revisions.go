@@ -0,0 +1,240 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// SnippetRevision is one version of a snippet's title and files.
+type SnippetRevision struct{
+	SnippetID int
+	Number int
+	Title string
+	// Only filled in by Revision
+	Files []SnippetFile
+	// Who made it, or 0 if they're unknown or gone
+	UserID int
+	Author string
+	Created time.Time
+	// The earlier revision this one put back, or 0
+	RestoredFrom int
+}
+
+// execer is what's shared by *sql.DB and *sql.Tx.
+type execer interface{
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// addRevision records a snippet's title and files as its next revision.
+// The caller holds the snippet's row lock, so numbers don't clash.
+func addRevision(tx *sql.Tx, snippetID int, title string, files []SnippetFile, userID int, restoredFrom int) (int, error){
+	var number int
+	err := tx.QueryRow(`SELECT COALESCE(MAX(number), 0) + 1 FROM snippet_revisions WHERE snippet_id = ?`, snippetID).Scan(&number)
+	if err != nil{
+		return 0, err
+	}
+
+	stmt := `INSERT INTO snippet_revisions (snippet_id, number, title, user_id, created, restored_from)
+	VALUES (?, ?, ?, NULLIF(?, 0), UTC_TIMESTAMP(), NULLIF(?, 0))`
+
+	_, err = tx.Exec(stmt, snippetID, number, title, userID, restoredFrom)
+	if err != nil{
+		return 0, err
+	}
+
+	for i, f := range files{
+		sum := sha256.Sum256([]byte(f.Content))
+		hash := hex.EncodeToString(sum[:])
+
+		_, err = tx.Exec(`INSERT IGNORE INTO snippet_blobs (hash, content) VALUES (?, ?)`, hash, f.Content)
+		if err != nil{
+			return 0, err
+		}
+
+		stmt = `INSERT INTO snippet_revision_files (snippet_id, number, position, name, language, content_hash)
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+		_, err = tx.Exec(stmt, snippetID, number, i, f.Name, f.Language, hash)
+		if err != nil{
+			return 0, err
+		}
+	}
+
+	return number, nil
+}
+
+// revisionBlobs returns the content hashes used by the revisions stmt selects
+// them from, so they can be pruned once those revisions are gone.
+func revisionBlobs(tx *sql.Tx, stmt string, args ...any) ([]string, error){
+	rows, err := tx.Query(stmt, args...)
+	if err != nil{
+		return nil, err
+	}
+	defer rows.Close()
+
+	hashes := []string{}
+
+	for rows.Next(){
+		var hash string
+
+		err = rows.Scan(&hash)
+		if err != nil{
+			return nil, err
+		}
+
+		hashes = append(hashes, hash)
+	}
+
+	if err = rows.Err(); err != nil{
+		return nil, err
+	}
+
+	return hashes, nil
+}
+
+// pruneBlobs deletes whichever of hashes no revision uses any more, so deleted
+// snippets don't leave their text behind.
+func pruneBlobs(db execer, hashes []string) error{
+	stmt := `DELETE FROM snippet_blobs WHERE hash = ?
+	AND NOT EXISTS (SELECT 1 FROM snippet_revision_files WHERE content_hash = ?)`
+
+	for _, hash := range hashes{
+		_, err := db.Exec(stmt, hash, hash)
+		if err != nil{
+			return err
+		}
+	}
+	return nil
+}
+
+// Update saves a new title and files for a snippet as its next revision. It
+// reports false, and saves nothing, if neither has changed.
+func (m *SnippetModel) Update(id int, title string, files []SnippetFile, userID int) (bool, error){
+	number, err := m.update(id, title, files, userID, 0)
+	return number != 0, err
+}
+
+// update returns the number of the revision added, or 0 if there was no
+// change.
+func (m *SnippetModel) update(id int, title string, files []SnippetFile, userID int, restoredFrom int) (int, error){
+	tx, err := m.DB.Begin()
+	if err != nil{
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var oldTitle string
+	stmt := `SELECT title FROM snippets WHERE id = ? AND expires > UTC_TIMESTAMP() FOR UPDATE`
+	err = tx.QueryRow(stmt, id).Scan(&oldTitle)
+	if err != nil{
+		if errors.Is(err, sql.ErrNoRows){
+			return 0, ErrNoRecord
+		}
+		return 0, err
+	}
+
+	oldFiles, err := snippetFiles(tx, id)
+	if err != nil{
+		return 0, err
+	}
+	if title == oldTitle && sameFiles(files, oldFiles){
+		return 0, nil
+	}
+
+	_, err = tx.Exec(`UPDATE snippets SET title = ? WHERE id = ?`, title, id)
+	if err != nil{
+		return 0, err
+	}
+
+	_, err = tx.Exec(`DELETE FROM snippet_files WHERE snippet_id = ?`, id)
+	if err != nil{
+		return 0, err
+	}
+	err = insertFiles(tx, id, files)
+	if err != nil{
+		return 0, err
+	}
+
+	number, err := addRevision(tx, id, title, files, userID, restoredFrom)
+	if err != nil{
+		return 0, err
+	}
+
+	return number, tx.Commit()
+}
+
+// Revisions returns a snippet's revisions, newest first, without their
+// files.
+func (m *SnippetModel) Revisions(id int) ([]*SnippetRevision, error){
+	stmt := `SELECT r.snippet_id, r.number, r.title, COALESCE(r.user_id, 0), COALESCE(u.name, ''), r.created, COALESCE(r.restored_from, 0)
+	FROM snippet_revisions r LEFT JOIN users u ON u.id = r.user_id
+	WHERE r.snippet_id = ? ORDER BY r.number DESC`
+
+	rows, err := m.DB.Query(stmt, id)
+	if err != nil{
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*SnippetRevision{}
+
+	for rows.Next(){
+		rev := &SnippetRevision{}
+
+		err = rows.Scan(&rev.SnippetID, &rev.Number, &rev.Title, &rev.UserID, &rev.Author, &rev.Created, &rev.RestoredFrom)
+		if err != nil{
+			return nil, err
+		}
+
+		revisions = append(revisions, rev)
+	}
+
+	if err = rows.Err(); err != nil{
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// Revision returns one revision of a snippet, with its files.
+func (m *SnippetModel) Revision(id, number int) (*SnippetRevision, error){
+	stmt := `SELECT r.snippet_id, r.number, r.title, COALESCE(r.user_id, 0), COALESCE(u.name, ''), r.created, COALESCE(r.restored_from, 0)
+	FROM snippet_revisions r LEFT JOIN users u ON u.id = r.user_id
+	WHERE r.snippet_id = ? AND r.number = ?`
+
+	rev := &SnippetRevision{}
+
+	err := m.DB.QueryRow(stmt, id, number).Scan(&rev.SnippetID, &rev.Number, &rev.Title, &rev.UserID, &rev.Author, &rev.Created, &rev.RestoredFrom)
+	if err != nil{
+		if errors.Is(err, sql.ErrNoRows){
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	stmt = `SELECT f.name, f.language, b.content
+	FROM snippet_revision_files f JOIN snippet_blobs b ON b.hash = f.content_hash
+	WHERE f.snippet_id = ? AND f.number = ? ORDER BY f.position`
+
+	rev.Files, err = scanFiles(m.DB, stmt, id, number)
+	if err != nil{
+		return nil, err
+	}
+
+	return rev, nil
+}
+
+// Restore makes an old revision's title and files current again, as a new
+// revision. It returns the new revision's number, or 0 if the snippet
+// already matched it.
+func (m *SnippetModel) Restore(id, number int, userID int) (int, error){
+	rev, err := m.Revision(id, number)
+	if err != nil{
+		return 0, err
+	}
+
+	return m.update(id, rev.Title, rev.Files, userID, number)
+}
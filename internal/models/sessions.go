@@ -0,0 +1,185 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// How often a session's last seen time is written, at most
+const sessionTouchInterval = time.Minute
+
+// UserSession describes one place a user is signed in.
+type UserSession struct{
+	ID string
+	UserID int
+	Created time.Time
+	LastSeen time.Time
+	IP string
+	UserAgent string
+	// The session making the request it was loaded for
+	Current bool
+	// Signed back in with a remember-me token when the session expires
+	Remembered bool
+}
+
+// SessionModel keeps track of signed-in sessions. The session data itself
+// lives in the session store; this is the part users can see, and deleting a
+// row signs that session out.
+type SessionModel struct{
+	DB *sql.DB
+	// Sessions older than this have expired from the store
+	Lifetime time.Duration
+}
+
+// Insert records a new session for the user and returns its ID. token is the
+// session's token in the session store.
+func (m *SessionModel) Insert(userID int, token, ip, userAgent string) (string, error){
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil{
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+
+	// Tidy up the user's expired sessions while we're here
+	stmt := `DELETE FROM user_sessions WHERE user_id = ? AND created < ? AND id NOT IN
+	(SELECT session_id FROM remember_tokens WHERE expiry > UTC_TIMESTAMP())`
+
+	_, err = m.DB.Exec(stmt, userID, m.cutoff())
+	if err != nil{
+		return "", err
+	}
+
+	stmt = `INSERT INTO user_sessions (id, user_id, token, created, last_seen, ip, user_agent)
+	VALUES(?, ?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP(), ?, ?)`
+
+	_, err = m.DB.Exec(stmt, id, userID, token, ip, truncate(userAgent, 255))
+	if err != nil{
+		return "", err
+	}
+	return id, nil
+}
+
+// Touch notes that a session is still in use from ip. It returns ErrNoRecord
+// if the session has been revoked.
+func (m *SessionModel) Touch(id string, userID int, ip string) error{
+	var lastSeen time.Time
+
+	stmt := `SELECT last_seen FROM user_sessions WHERE id = ? AND user_id = ?`
+
+	err := m.DB.QueryRow(stmt, id, userID).Scan(&lastSeen)
+	if err != nil{
+		if errors.Is(err, sql.ErrNoRows){
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	// Once a minute is plenty, and saves a write on every request
+	if time.Since(lastSeen) < sessionTouchInterval{
+		return nil
+	}
+
+	stmt = `UPDATE user_sessions SET last_seen = UTC_TIMESTAMP(), ip = ? WHERE id = ?`
+
+	_, err = m.DB.Exec(stmt, ip, id)
+	return err
+}
+
+// ForUser returns the user's unexpired sessions, most recently used first,
+// marking current. Sessions with a remember-me token last as long as it does.
+func (m *SessionModel) ForUser(userID int, current string) ([]*UserSession, error){
+	stmt := `SELECT s.id, s.user_id, s.created, s.last_seen, s.ip, s.user_agent, r.expiry IS NOT NULL
+	FROM user_sessions s LEFT JOIN remember_tokens r ON r.session_id = s.id AND r.expiry > UTC_TIMESTAMP()
+	WHERE s.user_id = ? AND (s.created >= ? OR r.expiry IS NOT NULL) ORDER BY s.last_seen DESC`
+
+	rows, err := m.DB.Query(stmt, userID, m.cutoff())
+	if err != nil{
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*UserSession{}
+
+	for rows.Next(){
+		s := &UserSession{}
+		err = rows.Scan(&s.ID, &s.UserID, &s.Created, &s.LastSeen, &s.IP, &s.UserAgent, &s.Remembered)
+		if err != nil{
+			return nil, err
+		}
+		s.Current = s.ID == current
+		sessions = append(sessions, s)
+	}
+
+	if err = rows.Err(); err != nil{
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// Delete revokes one of the user's sessions.
+func (m *SessionModel) Delete(id string, userID int) error{
+	result, err := m.DB.Exec(`DELETE FROM user_sessions WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil{
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil{
+		return err
+	}
+	if n == 0{
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// SetToken records a session's new token after it has been renewed.
+func (m *SessionModel) SetToken(id, token string) error{
+	_, err := m.DB.Exec(`UPDATE user_sessions SET token = ? WHERE id = ?`, token, id)
+	return err
+}
+
+// Tokens returns the session store tokens of all of the user's sessions
+// except keep, which may be empty.
+func (m *SessionModel) Tokens(userID int, keep string) ([]string, error){
+	stmt := `SELECT token FROM user_sessions WHERE user_id = ? AND id <> ? AND token <> ''`
+
+	rows, err := m.DB.Query(stmt, userID, keep)
+	if err != nil{
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []string{}
+
+	for rows.Next(){
+		var token string
+		err = rows.Scan(&token)
+		if err != nil{
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err = rows.Err(); err != nil{
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// DeleteAllForUser revokes all of the user's sessions except keep, which may
+// be empty.
+func (m *SessionModel) DeleteAllForUser(userID int, keep string) error{
+	_, err := m.DB.Exec(`DELETE FROM user_sessions WHERE user_id = ? AND id <> ?`, userID, keep)
+	return err
+}
+
+func (m *SessionModel) cutoff() time.Time{
+	return time.Now().UTC().Add(-m.Lifetime)
+}
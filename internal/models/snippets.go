@@ -9,20 +9,33 @@ import (
 type Snippet struct{
 	ID int
 	Title string
-	Content string
+	// Only filled in for a single snippet, by Get and ForUser
+	Files []SnippetFile
 	Created time.Time
 	Expires time.Time
+	// The user who created it, or 0 if it predates accounts
+	UserID int
+	// The snippet this is a fork of, or 0
+	ParentID int
 }
 
 type SnippetModel struct{
 	DB *sql.DB
 }
 
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error){
-	stmt := `INSERT INTO snippets(title, content, created, expires)
-	VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+// Insert adds a snippet that expires in expires days. parentID is the snippet
+// it was forked from, or 0. A fork expires no later than its source.
+func (m *SnippetModel) Insert(title string, files []SnippetFile, expires int, userID int, parentID int) (int, error){
+	tx, err := m.DB.Begin()
+	if err != nil{
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO snippets(title, created, expires, user_id, parent_id)
+	VALUES(?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY), ?, NULLIF(?, 0))`
 	
-	result, err := m.DB.Exec(stmt, title, content, expires)
+	result, err := tx.Exec(stmt, title, expires, userID, parentID)
 	if err != nil{
 		return 0, err
 	}
@@ -32,18 +45,41 @@ func (m *SnippetModel) Insert(title string, content string, expires int) (int, e
 		return 0, err
 	}
 
-	return int(id), nil
+	// A fork can't outlive its source, or it would keep the source's content
+	// up after its author meant it to go
+	if parentID != 0{
+		stmt = `UPDATE snippets s JOIN snippets p ON p.id = s.parent_id
+		SET s.expires = LEAST(s.expires, p.expires) WHERE s.id = ?`
+
+		_, err = tx.Exec(stmt, id)
+		if err != nil{
+			return 0, err
+		}
+	}
+
+	err = insertFiles(tx, int(id), files)
+	if err != nil{
+		return 0, err
+	}
+
+	// The first revision is the snippet as created
+	_, err = addRevision(tx, int(id), title, files, userID, 0)
+	if err != nil{
+		return 0, err
+	}
+
+	return int(id), tx.Commit()
 }
 
 func (m *SnippetModel) Get(id int) (*Snippet, error){
-	stmt := `SELECT id, title, content, created, expires FROM snippets
+	stmt := `SELECT id, title, created, expires, COALESCE(user_id, 0), COALESCE(parent_id, 0) FROM snippets
 	WHERE expires > UTC_TIMESTAMP() AND id = ?`
 
 	row := m.DB.QueryRow(stmt, id)
 
 	s := &Snippet{}
 
-	err := row.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	err := row.Scan(&s.ID, &s.Title, &s.Created, &s.Expires, &s.UserID, &s.ParentID)
 	if err != nil{
 		if errors.Is(err, sql.ErrNoRows){
 			return nil, ErrNoRecord
@@ -52,11 +88,16 @@ func (m *SnippetModel) Get(id int) (*Snippet, error){
 		}
 	}
 
+	s.Files, err = snippetFiles(m.DB, s.ID)
+	if err != nil{
+		return nil, err
+	}
+
 	return s, nil
 }
 
 func (m *SnippetModel) Latest() ([]*Snippet, error){
-	stmt := `SELECT id, title, content, created, expires FROM snippets
+	stmt := `SELECT id, title, created, expires, COALESCE(user_id, 0), COALESCE(parent_id, 0) FROM snippets
 	WHERE expires > UTC_TIMESTAMP() ORDER BY id DESC LIMIT 10`
 
 	rows, err := m.DB.Query(stmt);
@@ -70,7 +111,7 @@ func (m *SnippetModel) Latest() ([]*Snippet, error){
 	for rows.Next(){
 		s := &Snippet{}
 
-		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+		err = rows.Scan(&s.ID, &s.Title, &s.Created, &s.Expires, &s.UserID, &s.ParentID)
 		if err != nil{
 			return nil, err
 		}
@@ -83,4 +124,193 @@ func (m *SnippetModel) Latest() ([]*Snippet, error){
 	}
 
 	return snippets, nil
-}
\ No newline at end of file
+}
+
+func (m *SnippetModel) Delete(id int) error{
+	tx, err := m.DB.Begin()
+	if err != nil{
+		return err
+	}
+	defer tx.Rollback()
+
+	hashes, err := revisionBlobs(tx, `SELECT DISTINCT content_hash FROM snippet_revision_files WHERE snippet_id = ?`, id)
+	if err != nil{
+		return err
+	}
+
+	err = expireForks(tx, id)
+	if err != nil{
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM snippets WHERE id = ?`, id)
+	if err != nil{
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil{
+		return err
+	}
+	if n == 0{
+		return ErrNoRecord
+	}
+
+	err = pruneBlobs(tx, hashes)
+	if err != nil{
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Search returns a page of snippets whose title contains query, newest first,
+// and how many match in total. Unlike Latest it includes expired snippets.
+func (m *SnippetModel) Search(query string, limit, offset int) ([]*Snippet, int, error){
+	pattern := "%" + escapeLike(query) + "%"
+
+	var total int
+	stmt := `SELECT COUNT(*) FROM snippets WHERE title LIKE ?`
+	err := m.DB.QueryRow(stmt, pattern).Scan(&total)
+	if err != nil{
+		return nil, 0, err
+	}
+
+	stmt = `SELECT id, title, created, expires, COALESCE(user_id, 0), COALESCE(parent_id, 0) FROM snippets
+	WHERE title LIKE ? ORDER BY id DESC LIMIT ? OFFSET ?`
+
+	rows, err := m.DB.Query(stmt, pattern, limit, offset)
+	if err != nil{
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	snippets := []*Snippet{}
+
+	for rows.Next(){
+		s := &Snippet{}
+
+		err = rows.Scan(&s.ID, &s.Title, &s.Created, &s.Expires, &s.UserID, &s.ParentID)
+		if err != nil{
+			return nil, 0, err
+		}
+
+		snippets = append(snippets, s)
+	}
+
+	if err = rows.Err(); err != nil{
+		return nil, 0, err
+	}
+
+	return snippets, total, nil
+}
+
+// ForUser returns every snippet the user created, expired or not, oldest
+// first.
+func (m *SnippetModel) ForUser(userID int) ([]*Snippet, error){
+	stmt := `SELECT id, title, created, expires, user_id, COALESCE(parent_id, 0) FROM snippets
+	WHERE user_id = ? ORDER BY id`
+
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil{
+		return nil, err
+	}
+	defer rows.Close()
+
+	snippets := []*Snippet{}
+
+	for rows.Next(){
+		s := &Snippet{}
+
+		err = rows.Scan(&s.ID, &s.Title, &s.Created, &s.Expires, &s.UserID, &s.ParentID)
+		if err != nil{
+			return nil, err
+		}
+
+		snippets = append(snippets, s)
+	}
+
+	if err = rows.Err(); err != nil{
+		return nil, err
+	}
+
+	for _, s := range snippets{
+		s.Files, err = snippetFiles(m.DB, s.ID)
+		if err != nil{
+			return nil, err
+		}
+	}
+
+	return snippets, nil
+}
+
+// Forks returns the unexpired snippets forked from a snippet, newest first.
+func (m *SnippetModel) Forks(id int) ([]*Snippet, error){
+	stmt := `SELECT id, title, created, expires, COALESCE(user_id, 0), COALESCE(parent_id, 0) FROM snippets
+	WHERE parent_id = ? AND expires > UTC_TIMESTAMP() ORDER BY id DESC`
+
+	rows, err := m.DB.Query(stmt, id)
+	if err != nil{
+		return nil, err
+	}
+	defer rows.Close()
+
+	snippets := []*Snippet{}
+
+	for rows.Next(){
+		s := &Snippet{}
+
+		err = rows.Scan(&s.ID, &s.Title, &s.Created, &s.Expires, &s.UserID, &s.ParentID)
+		if err != nil{
+			return nil, err
+		}
+
+		snippets = append(snippets, s)
+	}
+
+	if err = rows.Err(); err != nil{
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// Expire makes a snippet expire now, if it hasn't already.
+func (m *SnippetModel) Expire(id int) error{
+	tx, err := m.DB.Begin()
+	if err != nil{
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `UPDATE snippets SET expires = UTC_TIMESTAMP() WHERE id = ? AND expires > UTC_TIMESTAMP()`
+
+	_, err = tx.Exec(stmt, id)
+	if err != nil{
+		return err
+	}
+
+	err = expireForks(tx, id)
+	if err != nil{
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// expireForks expires the forks of a snippet that is going early, and theirs
+// in turn, as they were made expecting to go no later than it does.
+func expireForks(tx *sql.Tx, id int) error{
+	stmt := `UPDATE snippets s JOIN (
+		WITH RECURSIVE forks (id) AS (
+			SELECT id FROM snippets WHERE parent_id = ?
+			UNION ALL
+			SELECT c.id FROM snippets c JOIN forks f ON c.parent_id = f.id
+		)
+		SELECT id FROM forks
+	) f ON f.id = s.id
+	SET s.expires = UTC_TIMESTAMP() WHERE s.expires > UTC_TIMESTAMP()`
+
+	_, err := tx.Exec(stmt, id)
+	return err
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Stats struct{
+	Users int
+	Admins int
+	DisabledUsers int
+	Snippets int
+	ActiveSnippets int
+	// Oldest first, with days nobody signed up left out
+	SignupsPerDay []DayCount
+}
+
+type DayCount struct{
+	Day time.Time
+	Count int
+}
+
+type StatsModel struct{
+	DB *sql.DB
+}
+
+// Get returns totals, and signups for each of the last days days.
+func (m *StatsModel) Get(days int) (*Stats, error){
+	s := &Stats{}
+
+	stmt := `SELECT COUNT(*), COALESCE(SUM(role = 'admin'), 0), COALESCE(SUM(disabled), 0) FROM users`
+	err := m.DB.QueryRow(stmt).Scan(&s.Users, &s.Admins, &s.DisabledUsers)
+	if err != nil{
+		return nil, err
+	}
+
+	stmt = `SELECT COUNT(*), COALESCE(SUM(expires > UTC_TIMESTAMP()), 0) FROM snippets`
+	err = m.DB.QueryRow(stmt).Scan(&s.Snippets, &s.ActiveSnippets)
+	if err != nil{
+		return nil, err
+	}
+
+	stmt = `SELECT DATE(created) AS day, COUNT(*) FROM users
+	WHERE created >= DATE_SUB(UTC_DATE(), INTERVAL ? DAY)
+	GROUP BY day ORDER BY day`
+
+	rows, err := m.DB.Query(stmt, days-1)
+	if err != nil{
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next(){
+		var d DayCount
+		err = rows.Scan(&d.Day, &d.Count)
+		if err != nil{
+			return nil, err
+		}
+		s.SignupsPerDay = append(s.SignupsPerDay, d)
+	}
+
+	if err = rows.Err(); err != nil{
+		return nil, err
+	}
+
+	return s, nil
+}
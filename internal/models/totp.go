@@ -0,0 +1,109 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+)
+
+type TOTPModel struct{
+	DB *sql.DB
+}
+
+// Get returns the user's secret and the last time step they used. An empty
+// secret means two-factor authentication is off.
+func (m *TOTPModel) Get(userID int) (string, int64, error){
+	var secret sql.NullString
+	var lastStep int64
+
+	stmt := `SELECT totp_secret, totp_last_step FROM users WHERE id = ?`
+
+	err := m.DB.QueryRow(stmt, userID).Scan(&secret, &lastStep)
+	if err != nil{
+		if errors.Is(err, sql.ErrNoRows){
+			return "", 0, ErrNoRecord
+		}
+		return "", 0, err
+	}
+	return secret.String, lastStep, nil
+}
+
+// Enable stores the secret and replaces any recovery codes with new ones.
+func (m *TOTPModel) Enable(userID int, secret string, recoveryCodes []string) error{
+	tx, err := m.DB.Begin()
+	if err != nil{
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE users SET totp_secret = ?, totp_last_step = 0 WHERE id = ?`, secret, userID)
+	if err != nil{
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM totp_recovery_codes WHERE user_id = ?`, userID)
+	if err != nil{
+		return err
+	}
+
+	for _, code := range recoveryCodes{
+		_, err = tx.Exec(`INSERT INTO totp_recovery_codes (user_id, hash) VALUES(?, ?)`, userID, hashToken(code))
+		if err != nil{
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m *TOTPModel) Disable(userID int) error{
+	tx, err := m.DB.Begin()
+	if err != nil{
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE users SET totp_secret = NULL, totp_last_step = 0 WHERE id = ?`, userID)
+	if err != nil{
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM totp_recovery_codes WHERE user_id = ?`, userID)
+	if err != nil{
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MarkUsed records that a code for step was accepted. It reports false if
+// that step (or a later one) was already used, i.e. the code is a replay.
+func (m *TOTPModel) MarkUsed(userID int, step int64) (bool, error){
+	stmt := `UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?`
+
+	result, err := m.DB.Exec(stmt, step, userID, step)
+	if err != nil{
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil{
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// UseRecoveryCode deletes the code if the user has it, so it works only once.
+func (m *TOTPModel) UseRecoveryCode(userID int, code string) (bool, error){
+	stmt := `DELETE FROM totp_recovery_codes WHERE user_id = ? AND hash = ?`
+
+	result, err := m.DB.Exec(stmt, userID, hashToken(code))
+	if err != nil{
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil{
+		return false, err
+	}
+	return n == 1, nil
+}
@@ -1,55 +1,159 @@
 package models
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
+	"github.com/AVSanjay-12/snippetbox/internal/passhash"
 	"github.com/go-sql-driver/mysql"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// Roles. Admins can manage users and delete any snippet.
+const (
+	RoleAdmin = "admin"
+	RoleMember = "member"
+)
+
+// ValidRole reports whether role is one of the roles above.
+func ValidRole(role string) bool{
+	return role == RoleAdmin || role == RoleMember
+}
+
 type User struct {
 	ID             int
 	Name           string
 	Email          string
 	HashedPassword []byte
+	Created        time.Time
+	EmailVerified  bool
+	TOTPEnabled    bool
+	Role           string
+	Disabled       bool
 	expiry         time.Time
 }
 
+// Authenticator checks a login and returns the local user it belongs to, or
+// ErrInvalidCredentials. UserModel checks the password stored here; other
+// implementations check it elsewhere.
+type Authenticator interface{
+	Authenticate(email, password string) (int, error)
+}
+
+// The columns scanUser expects, in order
+const userColumns = `id, name, email, created, email_verified, totp_secret IS NOT NULL, role, disabled`
+
+// scanUser reads a row selected with userColumns, from either *sql.Row or
+// *sql.Rows.
+func scanUser(row interface{ Scan(...any) error }) (*User, error){
+	u := &User{}
+	err := row.Scan(&u.ID, &u.Name, &u.Email, &u.Created, &u.EmailVerified, &u.TOTPEnabled, &u.Role, &u.Disabled)
+	if err != nil{
+		return nil, err
+	}
+	return u, nil
+}
+
 type UserModel struct{
 	DB *sql.DB
+	// How passwords are hashed; bcrypt with cost 12 if nil
+	Passwords *passhash.Policy
 }
 
-func (m *UserModel) Insert(name, email, password string) error{
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+func (m *UserModel) passwords() *passhash.Policy{
+	if m.Passwords == nil{
+		return &passhash.Policy{Current: &passhash.Bcrypt{Cost: 12}}
+	}
+	return m.Passwords
+}
+
+func (m *UserModel) Insert(name, email, password string) (int, error){
+	hashedPassword, err := m.passwords().Hash(password)
 	if err != nil{
-		return err
+		return 0, err
 	}
 
 	stmt := `INSERT INTO users (name, email, hashed_password, created)
 			VALUES(?, ?, ?, UTC_TIMESTAMP())`
 	
-	_, err = m.DB.Exec(stmt, name, email, string(hashedPassword))
+	result, err := m.DB.Exec(stmt, name, email, hashedPassword)
 	if err != nil{
 		var mySQLError *mysql.MySQLError
 		if errors.As(err, &mySQLError){
 			if mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, "users_uc_email"){
-				return ErrDuplicateEmail
+				return 0, ErrDuplicateEmail
 			}
 		}
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil{
+		return 0, err
+	}
+	return int(id), nil
+
+}
+
+// InsertExternal creates a user whose identity was vouched for elsewhere, e.g.
+// by single sign-on. Their email counts as verified, and they get a random
+// password they don't know - the forgot password flow can set a real one.
+func (m *UserModel) InsertExternal(name, email string) (int, error){
+	password, err := randomPassword()
+	if err != nil{
+		return 0, err
+	}
+
+	id, err := m.Insert(name, email, password)
+	if err != nil{
+		return 0, err
+	}
+
+	return id, m.SetEmailVerified(id)
+}
+
+// Reclaim hands an unverified account to the owner of its email address,
+// once they have proved it elsewhere. Whoever signed up with the address may
+// not have been them, so the password they chose is replaced with a random one.
+func (m *UserModel) Reclaim(id int) error{
+	password, err := randomPassword()
+	if err != nil{
+		return err
+	}
+
+	err = m.UpdatePassword(id, password)
+	if err != nil{
 		return err
 	}
-	return nil
 
+	return m.SetEmailVerified(id)
 }
 
+// escapeLike escapes the LIKE wildcards in s so it matches literally.
+func escapeLike(s string) string{
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func randomPassword() (string, error){
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil{
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Authenticate checks the password, and if its hash was made under an older,
+// weaker policy, replaces it with one made under the current one.
 func (m *UserModel) Authenticate(email, password string) (int, error){
 	var id int
-	var hashedPassword []byte
+	var hashedPassword string
 
-	stmt := "SELECT id, hashed_Password FROM users WHERE email = ?"
+	stmt := "SELECT id, hashed_password FROM users WHERE email = ?"
 
 	err := m.DB.QueryRow(stmt, email).Scan(&id, &hashedPassword)
 	if err != nil{
@@ -59,20 +163,238 @@ func (m *UserModel) Authenticate(email, password string) (int, error){
 		return 0, err
 	}
 
-	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	ok, rehash, err := m.passwords().Verify(hashedPassword, password)
 	if err != nil{
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword){
-			return 0, ErrInvalidCredentials
+		return 0, err
+	}
+	if !ok{
+		return 0, ErrInvalidCredentials
+	}
+
+	if rehash{
+		err = m.rehash(id, hashedPassword, password)
+		if err != nil{
+			return 0, err
 		}
-		return 0, nil
 	}
 	return id, nil
 }
 
+// rehash replaces the user's password hash with a fresh one, unless the
+// password was changed since old was read.
+func (m *UserModel) rehash(id int, old, password string) error{
+	hashedPassword, err := m.passwords().Hash(password)
+	if err != nil{
+		return err
+	}
+
+	stmt := `UPDATE users SET hashed_password = ? WHERE id = ? AND hashed_password = ?`
+
+	_, err = m.DB.Exec(stmt, hashedPassword, id, old)
+	return err
+}
+
 func (m *UserModel) Exists(id int) (bool, error){
 	var exists bool
 	stmt := "SELECT EXISTS(SELECT true FROM users WHERE id = ?)"
 	err := m.DB.QueryRow(stmt, id).Scan(&exists)
 	return exists, err
 
-}
\ No newline at end of file
+}
+
+func (m *UserModel) Get(id int) (*User, error){
+	stmt := `SELECT ` + userColumns + ` FROM users WHERE id = ?`
+
+	u, err := scanUser(m.DB.QueryRow(stmt, id))
+	if err != nil{
+		if errors.Is(err, sql.ErrNoRows){
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// Search returns a page of users whose name or email contains query (all
+// users if it is empty), oldest first, and how many match in total.
+func (m *UserModel) Search(query string, limit, offset int) ([]*User, int, error){
+	pattern := "%" + escapeLike(query) + "%"
+
+	var total int
+	stmt := `SELECT COUNT(*) FROM users WHERE name LIKE ? OR email LIKE ?`
+	err := m.DB.QueryRow(stmt, pattern, pattern).Scan(&total)
+	if err != nil{
+		return nil, 0, err
+	}
+
+	stmt = `SELECT ` + userColumns + ` FROM users WHERE name LIKE ? OR email LIKE ?
+	ORDER BY id LIMIT ? OFFSET ?`
+
+	rows, err := m.DB.Query(stmt, pattern, pattern, limit, offset)
+	if err != nil{
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+
+	for rows.Next(){
+		u, err := scanUser(rows)
+		if err != nil{
+			return nil, 0, err
+		}
+
+		users = append(users, u)
+	}
+
+	if err = rows.Err(); err != nil{
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// Delete removes a user and everything that hangs off their account. Their
+// snippets are deleted too, or with keepSnippets, kept without an owner.
+// Audit log entries are kept, as a record of what the account did.
+func (m *UserModel) Delete(id int, keepSnippets bool) error{
+	tx, err := m.DB.Begin()
+	if err != nil{
+		return err
+	}
+	defer tx.Rollback()
+
+	// Only content from deleted snippets can be left unused
+	hashes := []string{}
+	if !keepSnippets{
+		stmt := `SELECT DISTINCT r.content_hash FROM snippet_revision_files r
+		JOIN snippets s ON s.id = r.snippet_id WHERE s.user_id = ?`
+
+		hashes, err = revisionBlobs(tx, stmt, id)
+		if err != nil{
+			return err
+		}
+
+		_, err = tx.Exec(`DELETE FROM snippets WHERE user_id = ?`, id)
+		if err != nil{
+			return err
+		}
+	}
+
+	// Sessions, identities, tokens and recovery codes go with the user. So
+	// does ownership of any snippets left.
+	result, err := tx.Exec(`DELETE FROM users WHERE id = ?`, id)
+	if err != nil{
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil{
+		return err
+	}
+	if n == 0{
+		return ErrNoRecord
+	}
+
+	err = pruneBlobs(tx, hashes)
+	if err != nil{
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *UserModel) SetDisabled(id int, disabled bool) error{
+	stmt := `UPDATE users SET disabled = ? WHERE id = ?`
+
+	_, err := m.DB.Exec(stmt, disabled, id)
+	return err
+}
+
+func (m *UserModel) SetRole(id int, role string) error{
+	if !ValidRole(role){
+		return fmt.Errorf("models: invalid role %q", role)
+	}
+
+	stmt := `UPDATE users SET role = ? WHERE id = ?`
+
+	result, err := m.DB.Exec(stmt, role, id)
+	if err != nil{
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil{
+		return err
+	}
+	if n == 0{
+		// Setting a user's current role again also affects no rows
+		exists, err := m.Exists(id)
+		if err != nil{
+			return err
+		}
+		if !exists{
+			return ErrNoRecord
+		}
+	}
+	return nil
+}
+
+func (m *UserModel) SetEmailVerified(id int) error{
+	stmt := `UPDATE users SET email_verified = TRUE WHERE id = ?`
+
+	_, err := m.DB.Exec(stmt, id)
+	return err
+}
+
+func (m *UserModel) GetByEmail(email string) (*User, error){
+	stmt := `SELECT ` + userColumns + ` FROM users WHERE email = ?`
+
+	u, err := scanUser(m.DB.QueryRow(stmt, email))
+	if err != nil{
+		if errors.Is(err, sql.ErrNoRows){
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return u, nil
+}
+
+func (m *UserModel) UpdatePassword(id int, password string) error{
+	hashedPassword, err := m.passwords().Hash(password)
+	if err != nil{
+		return err
+	}
+
+	stmt := `UPDATE users SET hashed_password = ? WHERE id = ?`
+
+	_, err = m.DB.Exec(stmt, hashedPassword, id)
+	return err
+}
+
+// PasswordUpdate changes the password after checking the current one, which
+// is verified the same way Authenticate does it.
+func (m *UserModel) PasswordUpdate(id int, currentPassword, newPassword string) error{
+	var currentHashedPassword string
+
+	stmt := "SELECT hashed_password FROM users WHERE id = ?"
+
+	err := m.DB.QueryRow(stmt, id).Scan(&currentHashedPassword)
+	if err != nil{
+		if errors.Is(err, sql.ErrNoRows){
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	ok, _, err := m.passwords().Verify(currentHashedPassword, currentPassword)
+	if err != nil{
+		return err
+	}
+	if !ok{
+		return ErrInvalidCredentials
+	}
+
+	return m.UpdatePassword(id, newPassword)
+}
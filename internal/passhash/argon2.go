@@ -0,0 +1,128 @@
+package passhash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Defaults are OWASP's recommended minimum
+const (
+	argon2DefaultMemory = 19 * 1024
+	argon2DefaultIterations = 2
+	argon2DefaultParallelism = 1
+	argon2SaltLength = 16
+	argon2KeyLength = 32
+)
+
+var errBadArgon2Hash = errors.New("passhash: malformed argon2id hash")
+
+// Argon2id hashes passwords with argon2id, encoded in the PHC string format:
+// $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+type Argon2id struct{
+	// In KiB
+	Memory uint32
+	Iterations uint32
+	Parallelism uint8
+}
+
+type argon2Params struct{
+	memory uint32
+	iterations uint32
+	parallelism uint8
+	salt []byte
+	key []byte
+}
+
+func (a *Argon2id) params() argon2Params{
+	p := argon2Params{memory: a.Memory, iterations: a.Iterations, parallelism: a.Parallelism}
+	if p.memory == 0{
+		p.memory = argon2DefaultMemory
+	}
+	if p.iterations == 0{
+		p.iterations = argon2DefaultIterations
+	}
+	if p.parallelism == 0{
+		p.parallelism = argon2DefaultParallelism
+	}
+	return p
+}
+
+func (a *Argon2id) Hash(password string) (string, error){
+	p := a.params()
+
+	p.salt = make([]byte, argon2SaltLength)
+	_, err := rand.Read(p.salt)
+	if err != nil{
+		return "", err
+	}
+	p.key = argon2.IDKey([]byte(password), p.salt, p.iterations, p.memory, p.parallelism, argon2KeyLength)
+
+	b64 := base64.RawStdEncoding
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.iterations, p.parallelism, b64.EncodeToString(p.salt), b64.EncodeToString(p.key)), nil
+}
+
+func (a *Argon2id) Verify(encoded, password string) (bool, error){
+	p, err := decodeArgon2(encoded)
+	if err != nil{
+		return false, err
+	}
+
+	key := argon2.IDKey([]byte(password), p.salt, p.iterations, p.memory, p.parallelism, uint32(len(p.key)))
+	return subtle.ConstantTimeCompare(key, p.key) == 1, nil
+}
+
+func (a *Argon2id) Handles(encoded string) bool{
+	return hasPrefix(encoded, "$argon2id$")
+}
+
+func (a *Argon2id) Weaker(encoded string) bool{
+	p, err := decodeArgon2(encoded)
+	if err != nil{
+		return true
+	}
+
+	want := a.params()
+	return p.memory < want.memory || p.iterations < want.iterations || p.parallelism < want.parallelism ||
+		len(p.key) < argon2KeyLength
+}
+
+func decodeArgon2(encoded string) (argon2Params, error){
+	var p argon2Params
+
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id"{
+		return p, errBadArgon2Hash
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil{
+		return p, errBadArgon2Hash
+	}
+	if version != argon2.Version{
+		return p, fmt.Errorf("passhash: unsupported argon2 version %d", version)
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism)
+	if err != nil || p.memory == 0 || p.iterations == 0 || p.parallelism == 0{
+		return p, errBadArgon2Hash
+	}
+
+	p.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil{
+		return p, errBadArgon2Hash
+	}
+	p.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(p.key) == 0{
+		return p, errBadArgon2Hash
+	}
+	return p, nil
+}
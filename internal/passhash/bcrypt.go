@@ -0,0 +1,49 @@
+package passhash
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Bcrypt hashes passwords with bcrypt. Only the first 72 bytes of a password
+// count.
+type Bcrypt struct{
+	// bcrypt.DefaultCost if zero
+	Cost int
+}
+
+func (b *Bcrypt) cost() int{
+	if b.Cost == 0{
+		return bcrypt.DefaultCost
+	}
+	return b.Cost
+}
+
+func (b *Bcrypt) Hash(password string) (string, error){
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.cost())
+	if err != nil{
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (b *Bcrypt) Verify(encoded, password string) (bool, error){
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err != nil{
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword){
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (b *Bcrypt) Handles(encoded string) bool{
+	return hasPrefix(encoded, "$2a$", "$2b$", "$2y$")
+}
+
+func (b *Bcrypt) Weaker(encoded string) bool{
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < b.cost()
+}
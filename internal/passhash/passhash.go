@@ -0,0 +1,67 @@
+// Package passhash hashes and checks passwords. Hashes carry their algorithm
+// and parameters in a prefix - bcrypt's $2b$12$... or argon2id's
+// $argon2id$v=19$m=...,t=...,p=...$ - so a hash made under an old policy
+// still verifies after the policy changes, and can be spotted and upgraded.
+package passhash
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnknownHash means a hash isn't in any format this package knows.
+var ErrUnknownHash = errors.New("passhash: unknown hash format")
+
+// Hasher makes and checks hashes of one algorithm.
+type Hasher interface{
+	// Hash returns the encoded hash of password, with a fresh salt.
+	Hash(password string) (string, error)
+	// Verify reports whether password matches encoded, which must be in
+	// this Hasher's format. The parameters are taken from encoded.
+	Verify(encoded, password string) (bool, error)
+	// Handles reports whether encoded is in this Hasher's format.
+	Handles(encoded string) bool
+	// Weaker reports whether encoded was made with weaker parameters than
+	// this Hasher's.
+	Weaker(encoded string) bool
+}
+
+// Policy hashes new passwords with Current, and verifies hashes made by any
+// of the supported algorithms.
+type Policy struct{
+	Current Hasher
+}
+
+// Every algorithm a stored hash might use, with default parameters
+var known = []Hasher{&Bcrypt{}, &Argon2id{}}
+
+func (p *Policy) Hash(password string) (string, error){
+	return p.Current.Hash(password)
+}
+
+// Verify reports whether password matches encoded and, if so, whether the
+// hash should be replaced with one made under the current policy.
+func (p *Policy) Verify(encoded, password string) (ok bool, rehash bool, err error){
+	for _, h := range known{
+		if !h.Handles(encoded){
+			continue
+		}
+
+		ok, err = h.Verify(encoded, password)
+		if err != nil || !ok{
+			return false, false, err
+		}
+		return true, !p.Current.Handles(encoded) || p.Current.Weaker(encoded), nil
+	}
+	return false, false, ErrUnknownHash
+}
+
+// hasPrefix reports whether s starts with any of the prefixes.
+func hasPrefix(s string, prefixes ...string) bool{
+	for _, prefix := range prefixes{
+		if strings.HasPrefix(s, prefix){
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package passhash
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+)
+
+// Cheap parameters, so the tests run quickly
+var (
+	bcryptLow = &Bcrypt{Cost: 4}
+	bcryptHigh = &Bcrypt{Cost: 5}
+	argon2Low = &Argon2id{Memory: 64, Iterations: 1, Parallelism: 1}
+	argon2High = &Argon2id{Memory: 128, Iterations: 1, Parallelism: 1}
+)
+
+func TestPolicyVerify(t *testing.T){
+	tests := []struct{
+		name string
+		madeWith Hasher
+		current Hasher
+		password string
+		wantOK bool
+		wantRehash bool
+	}{
+		{name: "bcrypt, same cost", madeWith: bcryptLow, current: bcryptLow, password: "pa55word", wantOK: true},
+		{name: "bcrypt, cost raised", madeWith: bcryptLow, current: bcryptHigh, password: "pa55word", wantOK: true, wantRehash: true},
+		{name: "bcrypt, cost lowered", madeWith: bcryptHigh, current: bcryptLow, password: "pa55word", wantOK: true},
+		{name: "bcrypt, wrong password", madeWith: bcryptLow, current: bcryptHigh, password: "wrong"},
+		{name: "bcrypt to argon2id", madeWith: bcryptLow, current: argon2Low, password: "pa55word", wantOK: true, wantRehash: true},
+		{name: "argon2id, same parameters", madeWith: argon2Low, current: argon2Low, password: "pa55word", wantOK: true},
+		{name: "argon2id, memory raised", madeWith: argon2Low, current: argon2High, password: "pa55word", wantOK: true, wantRehash: true},
+		{name: "argon2id, wrong password", madeWith: argon2Low, current: argon2Low, password: "wrong"},
+		{name: "argon2id to bcrypt", madeWith: argon2Low, current: bcryptLow, password: "pa55word", wantOK: true, wantRehash: true},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			encoded, err := tt.madeWith.Hash("pa55word")
+			if err != nil{
+				t.Fatal(err)
+			}
+
+			p := &Policy{Current: tt.current}
+			ok, rehash, err := p.Verify(encoded, tt.password)
+			if err != nil{
+				t.Fatal(err)
+			}
+			assert.Equal(t, ok, tt.wantOK)
+			assert.Equal(t, rehash, tt.wantRehash)
+		})
+	}
+}
+
+func TestPolicyVerifyLegacyBcrypt(t *testing.T){
+	// A cost 12 hash as stored before hashers were configurable
+	const legacy = "$2a$12$6Bragsmd7TApM0IZ1B3PA.g34AQLuOEzEdpuIdyAwFH.pv8IwFD4S"
+
+	p := &Policy{Current: &Bcrypt{Cost: 12}}
+	ok, rehash, err := p.Verify(legacy, "pa55word")
+	if err != nil{
+		t.Fatal(err)
+	}
+	assert.Equal(t, ok, true)
+	assert.Equal(t, rehash, false)
+}
+
+func TestPolicyVerifyUnknown(t *testing.T){
+	p := &Policy{Current: bcryptLow}
+
+	_, _, err := p.Verify("5f4dcc3b5aa765d61d8327deb882cf99", "password")
+	assert.Equal(t, errors.Is(err, ErrUnknownHash), true)
+
+	_, _, err = p.Verify("$argon2id$v=19$m=64,t=1$c2FsdA$a2V5", "password")
+	assert.Equal(t, err != nil, true)
+}
@@ -0,0 +1,103 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RFC 6238 parameters, as expected by common authenticator apps
+const (
+	Digits = 6
+	Period = 30 * time.Second
+	// Steps either side of now that are still accepted, for clock drift
+	Skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecret returns a random 160-bit secret, base32 encoded.
+func NewSecret() (string, error){
+	b := make([]byte, 20)
+	_, err := rand.Read(b)
+	if err != nil{
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// URI returns the otpauth:// provisioning URI authenticator apps scan.
+func URI(issuer, account, secret string) string{
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(Digits))
+	v.Set("period", fmt.Sprint(int(Period.Seconds())))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + v.Encode()
+}
+
+// Step returns the time step t falls in.
+func Step(t time.Time) int64{
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// Code returns the code for a time step.
+func Code(secret string, step int64) (string, error){
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil{
+		return "", err
+	}
+
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++{
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%mod), nil
+}
+
+// Validate checks code against the steps around t. Steps up to and including
+// lastStep are refused, so each code works only once. It returns the step the
+// code matched, to be stored as the new lastStep.
+func Validate(secret, code string, t time.Time, lastStep int64) (int64, bool){
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != Digits{
+		return 0, false
+	}
+
+	now := Step(t)
+	for step := now - Skew; step <= now + Skew; step++{
+		if step <= lastStep{
+			continue
+		}
+
+		want, err := Code(secret, step)
+		if err != nil{
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1{
+			return step, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,62 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+)
+
+// The SHA-1 test vectors from RFC 6238 appendix B, truncated to six digits
+func TestCode(t *testing.T){
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct{
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+
+	for _, tt := range tests{
+		code, err := Code(secret, Step(time.Unix(tt.unix, 0)))
+		if err != nil{
+			t.Fatal(err)
+		}
+		assert.Equal(t, code, tt.want)
+	}
+}
+
+func TestValidate(t *testing.T){
+	secret, err := NewSecret()
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	code, err := Code(secret, Step(now))
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	step, ok := Validate(secret, code, now, 0)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, step, Step(now))
+
+	// Still accepted one step later, for clock drift
+	_, ok = Validate(secret, code, now.Add(Period), 0)
+	assert.Equal(t, ok, true)
+
+	// But not twice
+	_, ok = Validate(secret, code, now, step)
+	assert.Equal(t, ok, false)
+
+	// Nor long after
+	_, ok = Validate(secret, code, now.Add(5*Period), 0)
+	assert.Equal(t, ok, false)
+}
@@ -0,0 +1,137 @@
+package validator
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// bcrypt ignores anything past this many bytes
+const MaxPasswordBytes = 72
+
+// PasswordPolicy is what a new password has to satisfy.
+type PasswordPolicy struct{
+	// In characters
+	MinLength int
+	// In bytes, at most MaxPasswordBytes
+	MaxLength int
+	// Checked when set
+	Breached *BreachedList
+}
+
+// Check returns why the password doesn't meet the policy, or "" if it does.
+// name and email are the user's, which it mustn't contain.
+func (p *PasswordPolicy) Check(password, name, email string) (string, error){
+	if !MinChars(password, p.MinLength){
+		return fmt.Sprintf("Password should be at least %d characters", p.MinLength), nil
+	}
+	if len(password) > p.MaxLength{
+		return fmt.Sprintf("Password should be at most %d bytes", p.MaxLength), nil
+	}
+	if ContainsPersonalInfo(password, name, email){
+		return "Password must not contain your name or email address", nil
+	}
+
+	if p.Breached != nil{
+		breached, err := p.Breached.Contains(password)
+		if err != nil{
+			return "", err
+		}
+		if breached{
+			return "This password has appeared in a data breach, so it isn't safe to use. Please choose another.", nil
+		}
+	}
+	return "", nil
+}
+
+// ContainsPersonalInfo reports whether the password contains the email
+// address, or any word of three or more letters from the name or the part of
+// the address before the @, ignoring case.
+func ContainsPersonalInfo(password, name, email string) bool{
+	password = strings.ToLower(password)
+	email = strings.ToLower(email)
+
+	if email != "" && strings.Contains(password, email){
+		return true
+	}
+
+	local, _, _ := strings.Cut(email, "@")
+	words := strings.FieldsFunc(strings.ToLower(name)+" "+local, func(r rune) bool{
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, w := range words{
+		if utf8.RuneCountInString(w) >= 3 && strings.Contains(password, w){
+			return true
+		}
+	}
+	return false
+}
+
+//go:embed breached.txt
+var bundledBreached string
+
+// SHA-1 hashes from breached.txt
+var bundled = func() map[string]bool{
+	hashes := map[string]bool{}
+	for _, line := range strings.Split(bundledBreached, "\n"){
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#"){
+			hashes[line] = true
+		}
+	}
+	return hashes
+}()
+
+// BreachedList checks passwords against SHA-1 hashes of passwords known to
+// have been breached: a short list bundled with the program, and optionally
+// a local copy of Pwned Passwords.
+//
+// The local copy is laid out like the k-anonymity range API: a file for each
+// five hex digit prefix (e.g. 5BAA6.txt) listing the remaining 35 digits of
+// each hash, as "SUFFIX:COUNT" lines. Only the file for the password's prefix
+// is read.
+type BreachedList struct{
+	// Empty uses only the bundled list
+	Dir string
+}
+
+func (b *BreachedList) Contains(password string) (bool, error){
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	if bundled[hash]{
+		return true, nil
+	}
+	if b.Dir == ""{
+		return false, nil
+	}
+
+	f, err := os.Open(filepath.Join(b.Dir, hash[:5]+".txt"))
+	if err != nil{
+		if errors.Is(err, fs.ErrNotExist){
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	suffix := hash[5:]
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan(){
+		line, _, _ := strings.Cut(scanner.Text(), ":")
+		if strings.EqualFold(strings.TrimSpace(line), suffix){
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,59 @@
+package validator
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AVSanjay-12/snippetbox/internal/assert"
+)
+
+func TestPasswordPolicy(t *testing.T){
+	dir := t.TempDir()
+
+	// A local range file holding one extra breached password
+	sum := sha1.Sum([]byte("correct horse battery staple"))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	err := os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte("0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n"+hash[5:]+":42\r\n"), 0o644)
+	if err != nil{
+		t.Fatal(err)
+	}
+
+	policy := &PasswordPolicy{MinLength: 8, MaxLength: MaxPasswordBytes, Breached: &BreachedList{Dir: dir}}
+
+	tests := []struct{
+		name string
+		password string
+		wantOK bool
+	}{
+		{name: "Valid", password: "plum-kettle-orbit", wantOK: true},
+		{name: "Too short", password: "a1b2c3"},
+		{name: "Exactly 72 bytes", password: strings.Repeat("x", 71) + "y", wantOK: true},
+		{name: "Over 72 bytes", password: strings.Repeat("é", 37)},
+		{name: "Contains name", password: "ALICEwonderland"},
+		{name: "Contains email user", password: "xx-ajones-2024"},
+		{name: "Contains email", password: "ajones@example.com!"},
+		{name: "Bundled breached", password: "password123"},
+		{name: "Locally breached", password: "correct horse battery staple"},
+	}
+
+	for _, tt := range tests{
+		t.Run(tt.name, func(t *testing.T){
+			problem, err := policy.Check(tt.password, "Alice Jo", "ajones@example.com")
+			if err != nil{
+				t.Fatal(err)
+			}
+			assert.Equal(t, problem == "", tt.wantOK)
+		})
+	}
+}
+
+func TestContainsPersonalInfo(t *testing.T){
+	// Two-letter words are too common to rule out
+	assert.Equal(t, ContainsPersonalInfo("joanna-99-lights", "Jo Li", "jl@example.com"), false)
+	assert.Equal(t, ContainsPersonalInfo("my-name-is-BOB", "Bob", ""), true)
+	assert.Equal(t, ContainsPersonalInfo("correct-horse", "", ""), false)
+}
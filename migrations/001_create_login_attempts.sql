@@ -0,0 +1,6 @@
+-- Failed login attempts, keyed by account ("email:...") or client IP ("ip:...")
+CREATE TABLE login_attempts (
+    attempt_key VARCHAR(320) NOT NULL PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failure DATETIME NOT NULL
+);
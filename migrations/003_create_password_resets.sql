@@ -0,0 +1,7 @@
+-- Only a SHA-256 hash of each reset token is stored
+CREATE TABLE password_resets (
+    hash BINARY(32) NOT NULL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    expiry DATETIME NOT NULL,
+    CONSTRAINT password_resets_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
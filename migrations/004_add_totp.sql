@@ -0,0 +1,11 @@
+ALTER TABLE users ADD COLUMN totp_secret VARCHAR(64) NULL;
+-- Last TOTP time step used, so each code is accepted only once
+ALTER TABLE users ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+-- Only SHA-256 hashes of recovery codes are stored
+CREATE TABLE totp_recovery_codes (
+    user_id INTEGER NOT NULL,
+    hash BINARY(32) NOT NULL,
+    PRIMARY KEY (user_id, hash),
+    CONSTRAINT totp_recovery_codes_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
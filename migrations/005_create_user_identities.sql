@@ -0,0 +1,9 @@
+-- Links accounts at external identity providers to local users
+CREATE TABLE user_identities (
+    issuer VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    user_id INTEGER NOT NULL,
+    created DATETIME NOT NULL,
+    PRIMARY KEY (issuer, subject),
+    CONSTRAINT user_identities_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,6 @@
+-- admin or member
+ALTER TABLE users ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'member';
+
+-- Snippets created before accounts existed have no owner
+ALTER TABLE snippets ADD COLUMN user_id INTEGER NULL;
+ALTER TABLE snippets ADD CONSTRAINT snippets_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
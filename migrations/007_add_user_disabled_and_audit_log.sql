@@ -0,0 +1,16 @@
+-- Disabled accounts can't log in, and their sessions stop working
+ALTER TABLE users ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Append-only: the application never updates rows in this table
+CREATE TABLE audit_log (
+    id BIGINT NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    created DATETIME NOT NULL,
+    actor_id INTEGER NULL,
+    action VARCHAR(64) NOT NULL,
+    ip VARCHAR(45) NOT NULL,
+    user_agent VARCHAR(255) NOT NULL,
+    detail JSON NOT NULL
+);
+
+CREATE INDEX idx_audit_log_created ON audit_log(created);
+CREATE INDEX idx_audit_log_actor ON audit_log(actor_id, created);
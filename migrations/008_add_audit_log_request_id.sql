@@ -0,0 +1,5 @@
+ALTER TABLE audit_log ADD COLUMN request_id VARCHAR(64) NOT NULL DEFAULT '';
+
+-- Entries can be pruned for retention but never changed
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE ON audit_log
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
@@ -0,0 +1,13 @@
+-- What each signed-in session is, so users can see and revoke them. The row
+-- is the authority: a session whose row is gone is signed out.
+CREATE TABLE user_sessions (
+    id CHAR(32) NOT NULL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    created DATETIME NOT NULL,
+    last_seen DATETIME NOT NULL,
+    ip VARCHAR(45) NOT NULL,
+    user_agent VARCHAR(255) NOT NULL,
+    CONSTRAINT user_sessions_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_sessions_user ON user_sessions(user_id, created);
@@ -0,0 +1,14 @@
+-- Remember-me logins. Each session can have one token, which is replaced
+-- every time it is used; previous_hash is the one it replaced, so a stolen
+-- copy being used after its owner's shows up. Revoking the session deletes
+-- its token.
+CREATE TABLE remember_tokens (
+    session_id CHAR(32) NOT NULL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    hash BINARY(32) NOT NULL,
+    previous_hash BINARY(32) NULL,
+    rotated DATETIME NOT NULL,
+    expiry DATETIME NOT NULL,
+    CONSTRAINT remember_tokens_fk_session FOREIGN KEY (session_id) REFERENCES user_sessions(id) ON DELETE CASCADE,
+    CONSTRAINT remember_tokens_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+-- Room for argon2id hashes as well as bcrypt's 60 characters
+ALTER TABLE users MODIFY hashed_password VARCHAR(255) NOT NULL;
@@ -0,0 +1,33 @@
+-- Content is stored once per distinct text, so revisions that only change the
+-- title, or restore an old version, don't copy it again.
+CREATE TABLE snippet_blobs (
+    hash CHAR(64) NOT NULL PRIMARY KEY,
+    content TEXT NOT NULL
+);
+
+-- Every version of a snippet, numbered from 1. The snippets row holds the
+-- latest.
+CREATE TABLE snippet_revisions (
+    snippet_id INTEGER NOT NULL,
+    number INTEGER NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    content_hash CHAR(64) NOT NULL,
+    user_id INTEGER NULL,
+    created DATETIME NOT NULL,
+    restored_from INTEGER NULL,
+    PRIMARY KEY (snippet_id, number),
+    CONSTRAINT snippet_revisions_fk_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE,
+    CONSTRAINT snippet_revisions_fk_blob FOREIGN KEY (content_hash) REFERENCES snippet_blobs(hash),
+    CONSTRAINT snippet_revisions_fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Revisions are never changed, only deleted with their snippet
+CREATE TRIGGER snippet_revisions_immutable BEFORE UPDATE ON snippet_revisions
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'snippet_revisions are immutable';
+
+-- Existing snippets start with their current version as revision 1
+INSERT IGNORE INTO snippet_blobs (hash, content)
+SELECT SHA2(content, 256), content FROM snippets;
+
+INSERT INTO snippet_revisions (snippet_id, number, title, content_hash, user_id, created)
+SELECT id, 1, title, SHA2(content, 256), user_id, created FROM snippets;
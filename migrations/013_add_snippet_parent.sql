@@ -0,0 +1,4 @@
+-- The snippet a fork was made from. A fork never outlives its source: it is
+-- capped at the source's expiry, and expires with it if that goes early.
+ALTER TABLE snippets ADD COLUMN parent_id INTEGER NULL;
+ALTER TABLE snippets ADD CONSTRAINT snippets_fk_parent FOREIGN KEY (parent_id) REFERENCES snippets(id) ON DELETE SET NULL;
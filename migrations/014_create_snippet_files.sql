@@ -0,0 +1,40 @@
+-- A snippet is one or more named files. Existing snippets become a single
+-- file each.
+CREATE TABLE snippet_files (
+    snippet_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    language VARCHAR(32) NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    PRIMARY KEY (snippet_id, position),
+    CONSTRAINT snippet_files_uc_name UNIQUE (snippet_id, name),
+    CONSTRAINT snippet_files_fk_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
+
+INSERT INTO snippet_files (snippet_id, position, name, content)
+SELECT id, 0, 'snippet.txt', content FROM snippets;
+
+ALTER TABLE snippets DROP COLUMN content;
+
+-- The files in each revision, with content stored once in snippet_blobs as
+-- before
+CREATE TABLE snippet_revision_files (
+    snippet_id INTEGER NOT NULL,
+    number INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    language VARCHAR(32) NOT NULL DEFAULT '',
+    content_hash CHAR(64) NOT NULL,
+    PRIMARY KEY (snippet_id, number, position),
+    CONSTRAINT snippet_revision_files_fk_revision FOREIGN KEY (snippet_id, number) REFERENCES snippet_revisions(snippet_id, number) ON DELETE CASCADE,
+    CONSTRAINT snippet_revision_files_fk_blob FOREIGN KEY (content_hash) REFERENCES snippet_blobs(hash)
+);
+
+CREATE TRIGGER snippet_revision_files_immutable BEFORE UPDATE ON snippet_revision_files
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'snippet_revision_files are immutable';
+
+INSERT INTO snippet_revision_files (snippet_id, number, position, name, content_hash)
+SELECT snippet_id, number, 0, 'snippet.txt', content_hash FROM snippet_revisions;
+
+ALTER TABLE snippet_revisions DROP FOREIGN KEY snippet_revisions_fk_blob;
+ALTER TABLE snippet_revisions DROP COLUMN content_hash;
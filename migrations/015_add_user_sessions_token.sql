@@ -0,0 +1,4 @@
+-- The session store token for each session, so revoking a user's sessions can
+-- delete them from the store directly. Rows from before this are signed out
+-- by their row going, as before.
+ALTER TABLE user_sessions ADD COLUMN token VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+package ui
+
+import (
+	"embed"
+)
+
+// Files holds the HTML templates and static assets, so the binary can run
+// from any working directory.
+//
+//go:embed "html" "static"
+var Files embed.FS
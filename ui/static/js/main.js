@@ -0,0 +1,54 @@
+// Adds and removes snippet files in the page. Without JavaScript the same
+// buttons post the form back and the server does it instead.
+document.addEventListener("click", function (e) {
+  var button = e.target.closest("button[name=add_file], button[name=remove_file]");
+  if (!button) {
+    return;
+  }
+
+  var form = button.form;
+  var files = form.querySelectorAll("fieldset.file");
+
+  if (button.name === "add_file") {
+    e.preventDefault();
+    if (files.length >= Number(button.dataset.max)) {
+      return;
+    }
+    var copy = files[files.length - 1].cloneNode(true);
+    clearFile(copy);
+    files[files.length - 1].after(copy);
+  } else {
+    e.preventDefault();
+    var file = button.closest("fieldset.file");
+    if (files.length > 1) {
+      file.remove();
+    } else {
+      clearFile(file);
+    }
+  }
+
+  renumberFiles(form);
+});
+
+function clearFile(file) {
+  file.querySelectorAll("input, textarea").forEach(function (el) {
+    el.value = "";
+  });
+  file.querySelectorAll("select").forEach(function (el) {
+    el.selectedIndex = 0;
+  });
+  file.querySelectorAll("label.error").forEach(function (el) {
+    el.remove();
+  });
+}
+
+// Keeps the fields numbered files[0], files[1]... in page order, as the
+// server expects.
+function renumberFiles(form) {
+  form.querySelectorAll("fieldset.file").forEach(function (file, i) {
+    file.querySelectorAll("[name^='files[']").forEach(function (el) {
+      el.name = el.name.replace(/^files\[\d+\]/, "files[" + i + "]");
+    });
+    file.querySelector("button[name=remove_file]").value = i;
+  });
+}